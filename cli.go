@@ -0,0 +1,97 @@
+// Файл: cli.go
+package main
+
+import (
+	"fmt"
+	"frappuccino/models"
+	"frappuccino/repositories"
+	"io"
+	"os"
+)
+
+const cliUsage = `usage:
+  frappuccino                                  start the HTTP server
+  frappuccino import inventory|menu FILE.csv   load CSV (all rows are validated first)
+  frappuccino export inventory|menu [FILE.csv] write CSV to FILE or stdout`
+
+// runCLI выполняет import/export из командной строки и возвращает код выхода.
+func runCLI(args []string) int {
+	if len(args) < 2 {
+		fmt.Fprintln(os.Stderr, cliUsage)
+		return 2
+	}
+	command, target := args[0], args[1]
+
+	switch command {
+	case "import":
+		if len(args) != 3 {
+			fmt.Fprintln(os.Stderr, cliUsage)
+			return 2
+		}
+		file, err := os.Open(args[2])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer file.Close()
+
+		var result models.CSVImportResult
+		switch target {
+		case "inventory":
+			result, err = repositories.ImportInventoryCSV(file)
+		case "menu":
+			result, err = repositories.ImportMenuCSV(file)
+		default:
+			fmt.Fprintln(os.Stderr, cliUsage)
+			return 2
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, e := range result.Errors {
+			if e.Column != "" {
+				fmt.Fprintf(os.Stderr, "row %d, %s: %s\n", e.Row, e.Column, e.Message)
+			} else {
+				fmt.Fprintf(os.Stderr, "row %d: %s\n", e.Row, e.Message)
+			}
+		}
+		if len(result.Errors) > 0 {
+			fmt.Fprintf(os.Stderr, "%d errors, nothing imported\n", len(result.Errors))
+			return 1
+		}
+		fmt.Printf("imported %d %s rows\n", result.Imported, target)
+		return 0
+
+	case "export":
+		var out io.Writer = os.Stdout
+		if len(args) == 3 {
+			file, err := os.Create(args[2])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			defer file.Close()
+			out = file
+		}
+
+		var err error
+		switch target {
+		case "inventory":
+			err = repositories.ExportInventoryCSV(out)
+		case "menu":
+			err = repositories.ExportMenuCSV(out)
+		default:
+			fmt.Fprintln(os.Stderr, cliUsage)
+			return 2
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Fprintln(os.Stderr, cliUsage)
+	return 2
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"log"
 	"os"
 
 	_ "github.com/lib/pq"
@@ -30,6 +31,6 @@ func InitDB() (*sql.DB, error) {
 		return nil, err
 	}
 
-	fmt.Println("Successfully connected to the database!")
+	log.Println("Successfully connected to the database!")
 	return db, nil
 }
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"frappuccino/models"
+	"frappuccino/repositories"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const maxCSVUploadSize = 10 << 20 // 10 МБ
+
+// csvBody принимает CSV как тело запроса (text/csv) или как поле file формы multipart.
+func csvBody(w http.ResponseWriter, r *http.Request) (io.Reader, func(), bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxCSVUploadSize)
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Не удалось прочитать файл из формы: "+err.Error(), http.StatusBadRequest)
+			return nil, nil, false
+		}
+		return file, func() { file.Close() }, true
+	}
+	return r.Body, func() {}, true
+}
+
+func writeImportResult(w http.ResponseWriter, result models.CSVImportResult, err error) {
+	if err != nil {
+		http.Error(w, "Ошибка импорта: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if len(result.Errors) > 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+func writeCSVHeaders(w http.ResponseWriter, filename string) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+}
+
+func ImportInventoryCSVHandler(w http.ResponseWriter, r *http.Request) {
+	body, closeBody, ok := csvBody(w, r)
+	if !ok {
+		return
+	}
+	defer closeBody()
+
+	result, err := repositories.ImportInventoryCSV(body)
+	if err != nil {
+		log.Printf("[ImportInventoryCSV] %v", err)
+	}
+	writeImportResult(w, result, err)
+}
+
+func ExportInventoryCSVHandler(w http.ResponseWriter, r *http.Request) {
+	writeCSVHeaders(w, "inventory.csv")
+	if err := repositories.ExportInventoryCSV(w); err != nil {
+		// Заголовки могли уже уйти клиенту, поэтому только логируем
+		log.Printf("[ExportInventoryCSV] %v", err)
+	}
+}
+
+func ImportMenuCSVHandler(w http.ResponseWriter, r *http.Request) {
+	body, closeBody, ok := csvBody(w, r)
+	if !ok {
+		return
+	}
+	defer closeBody()
+
+	result, err := repositories.ImportMenuCSV(body)
+	if err != nil {
+		log.Printf("[ImportMenuCSV] %v", err)
+	}
+	writeImportResult(w, result, err)
+}
+
+func ExportMenuCSVHandler(w http.ResponseWriter, r *http.Request) {
+	writeCSVHeaders(w, "menu.csv")
+	if err := repositories.ExportMenuCSV(w); err != nil {
+		log.Printf("[ExportMenuCSV] %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"frappuccino/repositories"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ETag строится из версии строки: "v3". Версия растёт при каждом изменении.
+func formatETag(version int) string {
+	return fmt.Sprintf(`"v%d"`, version)
+}
+
+// parseETag принимает "v3" и слабую форму W/"v3".
+func parseETag(tag string) (int, error) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, fmt.Errorf("неверный формат ETag: %s", tag)
+	}
+	version, err := strconv.Atoi(strings.TrimPrefix(tag[1:len(tag)-1], "v"))
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("неверный формат ETag: %s", tag)
+	}
+	return version, nil
+}
+
+// requireIfMatch достаёт ожидаемую версию из If-Match. Без заголовка изменение
+// запрещено (428), чтобы клиент не мог случайно затереть чужую правку.
+// Список тегов и "*" не поддерживаются: нужна конкретная версия.
+func requireIfMatch(w http.ResponseWriter, r *http.Request) (int, bool) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		http.Error(w, "Заголовок If-Match обязателен", http.StatusPreconditionRequired)
+		return 0, false
+	}
+	version, err := parseETag(header)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		return 0, false
+	}
+	return version, true
+}
+
+// writeVersionError отвечает на ошибку условного изменения: 404, 412, 409 или 400.
+func writeVersionError(w http.ResponseWriter, prefix string, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		http.Error(w, prefix+err.Error(), http.StatusNotFound)
+	case errors.Is(err, repositories.ErrStale):
+		http.Error(w, prefix+err.Error(), http.StatusPreconditionFailed)
+	case errors.Is(err, repositories.ErrConflict):
+		http.Error(w, prefix+err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, prefix+err.Error(), http.StatusBadRequest)
+	}
+}
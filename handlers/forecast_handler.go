@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"encoding/json"
+	"frappuccino/repositories"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultForecastDays = 14
+	maxForecastDays     = 90
+	maxForecastHistory  = 365
+)
+
+// GetInventoryForecastHandler: ?days=N — горизонт прогноза (1..90, по умолчанию 14),
+// ?history=N — сколько дней истории учитывать (7..365, по умолчанию 56).
+func GetInventoryForecastHandler(w http.ResponseWriter, r *http.Request) {
+	days := defaultForecastDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxForecastDays {
+			http.Error(w, "days должен быть числом от 1 до 90", http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+
+	history := repositories.DefaultForecastHistoryDays
+	if v := r.URL.Query().Get("history"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 7 || n > maxForecastHistory {
+			http.Error(w, "history должен быть числом от 7 до 365", http.StatusBadRequest)
+			return
+		}
+		history = n
+	}
+
+	forecast, err := repositories.GetIngredientForecast(days, history)
+	if err != nil {
+		http.Error(w, "Ошибка при построении прогноза: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(forecast)
+}
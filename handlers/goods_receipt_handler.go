@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"frappuccino/models"
+	"frappuccino/repositories"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func CreateGoodsReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	var receipt models.GoodsReceipt
+	err := json.NewDecoder(r.Body).Decode(&receipt)
+	if err != nil {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateGoodsReceipt(receipt); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Приёмка по заказу поставщику идёт через /purchase-orders/{id}/receive
+	receipt.PurchaseOrderID = nil
+	for i := range receipt.Lines {
+		receipt.Lines[i].PurchaseOrderLineID = nil
+	}
+
+	created, err := repositories.CreateGoodsReceipt(receipt)
+	if errors.Is(err, repositories.ErrNotFound) {
+		http.Error(w, "Неверная строка поступления: "+err.Error(), http.StatusBadRequest)
+		return
+	} else if errors.Is(err, repositories.ErrConflict) {
+		http.Error(w, "Неверная строка поступления: "+err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, "Ошибка при проведении поступления: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func validateGoodsReceipt(receipt models.GoodsReceipt) error {
+	if len(receipt.Lines) == 0 {
+		return fmt.Errorf("поступление должно содержать хотя бы одну строку")
+	}
+	receivedAt := receipt.ReceivedAt
+	if receivedAt.IsZero() {
+		receivedAt = time.Now()
+	}
+	for i, line := range receipt.Lines {
+		if line.InventoryID <= 0 {
+			return fmt.Errorf("строка %d: inventory_id обязателен", i+1)
+		}
+		if line.Quantity <= 0 {
+			return fmt.Errorf("строка %d: количество должно быть больше 0", i+1)
+		}
+		if line.UnitCost < 0 {
+			return fmt.Errorf("строка %d: цена за единицу не может быть отрицательной", i+1)
+		}
+		if line.ExpiresAt != nil && !line.ExpiresAt.After(receivedAt) {
+			return fmt.Errorf("строка %d: срок годности должен быть позже даты поступления", i+1)
+		}
+	}
+	return nil
+}
+
+func GetGoodsReceiptsHandler(w http.ResponseWriter, r *http.Request) {
+	receipts, err := repositories.GetGoodsReceipts()
+	if err != nil {
+		http.Error(w, "Ошибка при получении поступлений: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(receipts)
+}
+
+func GetGoodsReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/inventory/receipts/")
+	if id == "" {
+		http.Error(w, "ID не указан", http.StatusBadRequest)
+		return
+	}
+
+	receipt, err := repositories.GetGoodsReceiptByID(id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Ошибка при получении поступления: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(receipt)
+}
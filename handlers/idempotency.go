@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"frappuccino/models"
+	"frappuccino/repositories"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+const maxIdempotencyKeyLength = 255
+
+const maxIdempotentBodySize = 1 << 20 // 1 МБ
+
+// idempotencyLease — сколько ключ может оставаться «в работе». Если процесс упал,
+// не успев сохранить ответ, по истечении этого срока повтор занимает ключ заново.
+const idempotencyLease = 60 * time.Second
+
+// idempotencyTTL — сколько хранится ответ по ключу. Задаётся переменной
+// окружения IDEMPOTENCY_TTL в формате time.ParseDuration, по умолчанию 24h.
+func idempotencyTTL() time.Duration {
+	if v := os.Getenv("IDEMPOTENCY_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err == nil && ttl > 0 {
+			return ttl
+		}
+		log.Printf("[Idempotency] Invalid IDEMPOTENCY_TTL %q, using 24h", v)
+	}
+	return 24 * time.Hour
+}
+
+// responseRecorder пишет ответ клиенту и одновременно запоминает его.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// WithIdempotency учитывает заголовок Idempotency-Key: первый ответ сохраняется
+// и повторяется для таких же повторов, тот же ключ с другим телом отклоняется (422),
+// а повтор, пришедший пока первый запрос ещё выполняется, получает 409.
+// Ответы 5xx не сохраняются, чтобы повтор мог пройти заново; ключ освобождается
+// и при панике обработчика, и если ответ не удалось сохранить.
+func WithIdempotency(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			http.Error(w, "Idempotency-Key слишком длинный", http.StatusBadRequest)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxIdempotentBodySize)
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Не удалось прочитать тело запроса", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		sum := sha256.Sum256(body)
+		hash := hex.EncodeToString(sum[:])
+
+		reserved, reservedAt, stored, err := repositories.ReserveIdempotencyKey(scope, key, hash, idempotencyTTL(), idempotencyLease)
+		if err != nil {
+			http.Error(w, "Ошибка при проверке Idempotency-Key: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if !reserved {
+			switch {
+			case stored.RequestHash != hash:
+				http.Error(w, "Idempotency-Key уже использован с другим телом запроса", http.StatusUnprocessableEntity)
+			case stored.StatusCode == 0:
+				http.Error(w, "Запрос с этим Idempotency-Key ещё выполняется", http.StatusConflict)
+			default:
+				if stored.ContentType != "" {
+					w.Header().Set("Content-Type", stored.ContentType)
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(stored.StatusCode)
+				w.Write(stored.Body)
+			}
+			return
+		}
+
+		release := func() {
+			if err := repositories.ReleaseIdempotencyKey(scope, key, reservedAt); err != nil {
+				log.Printf("[Idempotency] %v", err)
+			}
+		}
+		defer func() {
+			if p := recover(); p != nil {
+				release()
+				panic(p)
+			}
+		}()
+
+		rec := &responseRecorder{ResponseWriter: w}
+		next(rec, r)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		if rec.status >= 500 {
+			release()
+			return
+		}
+
+		err = repositories.SaveIdempotentResponse(scope, key, reservedAt, models.IdempotentResponse{
+			RequestHash: hash,
+			StatusCode:  rec.status,
+			ContentType: rec.Header().Get("Content-Type"),
+			Body:        rec.body.Bytes(),
+		})
+		if err != nil {
+			// Без сохранённого ответ повторы получали бы 409 до конца TTL.
+			// Если ключ уже перехватил повтор, release его резервацию не тронет.
+			log.Printf("[Idempotency] %v", err)
+			release()
+		}
+	}
+}
@@ -2,15 +2,60 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"frappuccino/models"
 	"frappuccino/repositories"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 )
 
+// parseAsOf читает ?as_of= — момент в прошлом в формате RFC3339
+// или локальное время "2006-01-02T15:04" / "2006-01-02 15:04".
+func parseAsOf(r *http.Request) (*time.Time, error) {
+	v := r.URL.Query().Get("as_of")
+	if v == "" {
+		return nil, nil
+	}
+
+	var t time.Time
+	var err error
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02T15:04:05", "2006-01-02 15:04:05"} {
+		t, err = time.ParseInLocation(layout, v, time.Local)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		t, err = time.Parse(time.RFC3339, v)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("as_of должен быть в формате RFC3339 или YYYY-MM-DDTHH:MM")
+	}
+	if t.After(time.Now()) {
+		return nil, fmt.Errorf("as_of не может быть в будущем")
+	}
+	return &t, nil
+}
+
+// GetInventoryHandler: ?as_of= возвращает остатки на прошлый момент,
+// восстановленные по журналу движений.
 func GetInventoryHandler(w http.ResponseWriter, r *http.Request) {
-	items, err := repositories.GetInventoryItems()
+	includeArchived := r.URL.Query().Get("include_archived") == "true"
+	asOf, err := parseAsOf(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var items []models.InventoryItem
+	if asOf != nil {
+		items, err = repositories.GetInventoryItemsAsOf(includeArchived, *asOf)
+	} else {
+		items, err = repositories.GetInventoryItems(includeArchived)
+	}
 	if err != nil {
 		http.Error(w, "Не удалось получить инвентарь: "+err.Error(), http.StatusInternalServerError)
 		log.Println("Ошибка получения инвентаря:", err)
@@ -51,6 +96,14 @@ func CreateInventoryHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Price per unit must be greater than 0", http.StatusBadRequest)
 		return
 	}
+	if item.ParLevel != nil && *item.ParLevel < 0 {
+		http.Error(w, "Par level must not be negative", http.StatusBadRequest)
+		return
+	}
+	if item.LeadTimeDays != nil && *item.LeadTimeDays < 0 {
+		http.Error(w, "Lead time must not be negative", http.StatusBadRequest)
+		return
+	}
 
 	id, err := repositories.CreateInventoryItems(item)
 	if err != nil {
@@ -72,12 +125,31 @@ func GetInventoryByIDHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	item, err := repositories.GetInventoryItemByID(id)
+	asOf, err := parseAsOf(r)
 	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var item models.InventoryItem
+	if asOf != nil {
+		item, err = repositories.GetInventoryItemAsOf(id, *asOf)
+	} else {
+		item, err = repositories.GetInventoryItemByID(id)
+	}
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			http.Error(w, "Не удалось получить элемент инвентаря: "+err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, "Не удалось получить элемент инвентаря: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	// Остаток на прошлый момент нельзя использовать для If-Match
+	if asOf == nil {
+		w.Header().Set("ETag", formatETag(item.Version))
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(item)
 }
@@ -90,6 +162,11 @@ func UpdateInventoryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	expectedVersion, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+
 	var item models.InventoryItem
 
 	// Декодируем JSON
@@ -116,17 +193,28 @@ func UpdateInventoryHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Price per unit must be greater than 0", http.StatusBadRequest)
 		return
 	}
+	if item.ParLevel != nil && *item.ParLevel < 0 {
+		http.Error(w, "Par level must not be negative", http.StatusBadRequest)
+		return
+	}
+	if item.LeadTimeDays != nil && *item.LeadTimeDays < 0 {
+		http.Error(w, "Lead time must not be negative", http.StatusBadRequest)
+		return
+	}
 
 	// Обновляем в БД
-	err = repositories.UpdateInventoryItem(id, item)
+	version, err := repositories.UpdateInventoryItem(id, item, expectedVersion)
 	if err != nil {
-		http.Error(w, "Не удалось обновить элемент инвентаря: "+err.Error(), http.StatusBadRequest)
+		writeVersionError(w, "Не удалось обновить элемент инвентаря: ", err)
 		return
 	}
 
+	w.Header().Set("ETag", formatETag(version))
 	w.WriteHeader(http.StatusOK)
 }
 
+// DeleteInventoryHandler: ?dry_run=true показывает план без изменений,
+// ?force=true разрешает удалить ингредиент, который входит в рецепты.
 func DeleteInventoryHandler(w http.ResponseWriter, r *http.Request) {
 	id := strings.TrimPrefix(r.URL.Path, "/inventory/")
 
@@ -135,11 +223,34 @@ func DeleteInventoryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := repositories.DeleteInventoryItem(id)
-	if err != nil {
-		http.Error(w, "Не удалось удалить элемент инвентаря: "+err.Error(), http.StatusBadRequest)
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	force := r.URL.Query().Get("force") == "true"
+
+	// Пробный прогон ничего не меняет, поэтому If-Match для него не нужен
+	var expectedVersion int
+	if !dryRun {
+		var ok bool
+		expectedVersion, ok = requireIfMatch(w, r)
+		if !ok {
+			return
+		}
+	}
+
+	plan, err := repositories.DeleteInventoryItem(id, expectedVersion, force, dryRun)
+	if errors.Is(err, repositories.ErrConflict) && plan.Name != "" {
+		// Ингредиент в рецептах: отдаём план, чтобы было видно, какие блюда изменятся
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(struct {
+			Error string `json:"error"`
+			models.InventoryDeletePlan
+		}{err.Error(), plan})
+		return
+	} else if err != nil {
+		writeVersionError(w, "Не удалось удалить элемент инвентаря: ", err)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent) // 204 No Content
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
 }
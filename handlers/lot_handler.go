@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"encoding/json"
+	"frappuccino/repositories"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultExpiringWithin = 48 * time.Hour
+
+// parseWithin принимает формат time.ParseDuration (48h, 90m) и дни (3d).
+func parseWithin(v string) (time.Duration, bool) {
+	if strings.HasSuffix(v, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(v, "d"))
+		if err != nil || days < 0 {
+			return 0, false
+		}
+		return time.Duration(days) * 24 * time.Hour, true
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, false
+	}
+	return d, true
+}
+
+// GetExpiringLotsHandler: ?within=48h — партии, срок годности которых истекает
+// в этот период, вместе с уже просроченными, но ещё не списанными.
+func GetExpiringLotsHandler(w http.ResponseWriter, r *http.Request) {
+	within := defaultExpiringWithin
+	if v := r.URL.Query().Get("within"); v != "" {
+		d, ok := parseWithin(v)
+		if !ok {
+			http.Error(w, "within должен быть длительностью, например 48h или 3d", http.StatusBadRequest)
+			return
+		}
+		within = d
+	}
+
+	lots, err := repositories.GetExpiringLots(within)
+	if err != nil {
+		http.Error(w, "Ошибка при получении партий: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lots)
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"frappuccino/models"
+	"frappuccino/repositories"
+	"net/http"
+	"strings"
+)
+
+func GetLoyaltyRulesHandler(w http.ResponseWriter, r *http.Request) {
+	rules, err := repositories.GetLoyaltyRules()
+	if err != nil {
+		http.Error(w, "Ошибка при получении правил начисления: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+func CreateLoyaltyRuleHandler(w http.ResponseWriter, r *http.Request) {
+	rule := models.LoyaltyRule{Active: true}
+
+	err := json.NewDecoder(r.Body).Decode(&rule)
+	if err != nil {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return
+	}
+
+	if rule.Name == "" {
+		http.Error(w, "Название правила обязательно", http.StatusBadRequest)
+		return
+	}
+	if rule.PointsPerUnit < 0 {
+		http.Error(w, "Баллы за единицу не могут быть отрицательными", http.StatusBadRequest)
+		return
+	}
+
+	id, err := repositories.CreateLoyaltyRule(rule)
+	if err != nil {
+		http.Error(w, "Ошибка при создании правила начисления: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]int{"id": id}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func GetLoyaltyTiersHandler(w http.ResponseWriter, r *http.Request) {
+	tiers, err := repositories.GetLoyaltyTiers()
+	if err != nil {
+		http.Error(w, "Ошибка при получении уровней: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tiers)
+}
+
+func CreateLoyaltyTierHandler(w http.ResponseWriter, r *http.Request) {
+	tier := models.LoyaltyTier{PointsMultiplier: 1}
+
+	err := json.NewDecoder(r.Body).Decode(&tier)
+	if err != nil {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return
+	}
+
+	if tier.Name == "" {
+		http.Error(w, "Название уровня обязательно", http.StatusBadRequest)
+		return
+	}
+	if tier.MinSpend < 0 {
+		http.Error(w, "Порог трат не может быть отрицательным", http.StatusBadRequest)
+		return
+	}
+	if tier.PointsMultiplier <= 0 {
+		http.Error(w, "Множитель баллов должен быть больше 0", http.StatusBadRequest)
+		return
+	}
+
+	id, err := repositories.CreateLoyaltyTier(tier)
+	if err != nil {
+		http.Error(w, "Ошибка при создании уровня: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := map[string]int{"id": id}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GetCustomerLoyaltyHandler — GET /customers/{id}/loyalty: баланс, траты и уровень
+func GetCustomerLoyaltyHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/customers/"), "/loyalty")
+	if id == "" {
+		http.Error(w, "ID не указан", http.StatusBadRequest)
+		return
+	}
+
+	loyalty, err := repositories.GetCustomerLoyalty(id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Ошибка при получении баллов: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(loyalty)
+}
+
+// GetCustomerLedgerHandler — GET /customers/{id}/loyalty/ledger: журнал начислений и списаний
+func GetCustomerLedgerHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/customers/"), "/loyalty/ledger")
+	if id == "" {
+		http.Error(w, "ID не указан", http.StatusBadRequest)
+		return
+	}
+
+	ledger, err := repositories.GetCustomerLedger(id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Ошибка при получении журнала баллов: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ledger)
+}
@@ -2,7 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"frappuccino/db"
 	"frappuccino/models"
 	"frappuccino/repositories"
@@ -44,12 +44,27 @@ func CreateMenuItemHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	validSizes := []string{"small", "medium", "large"}
-	if !utils.IsValidSize(validSizes, item.Size) {
+	// Блюдо с вариантами может не иметь собственного размера
+	if !(item.Size == "" && len(item.Variants) > 0) && !utils.IsValidSize(validSizes, item.Size) {
 		http.Error(w, "Invalid size", http.StatusBadRequest)
 		log.Printf("Invalid size: %s", item.Size)
 		return
 	}
 
+	err = utils.ValidateVariants(validSizes, item.Variants)
+	if err != nil {
+		http.Error(w, "Variant validation failed: "+err.Error(), http.StatusBadRequest)
+		log.Printf("Variant validation failed: %v", err)
+		return
+	}
+
+	err = utils.ValidateModifierIDs(item.ModifierIDs)
+	if err != nil {
+		http.Error(w, "Modifier validation failed: "+err.Error(), http.StatusBadRequest)
+		log.Printf("Modifier validation failed: %v", err)
+		return
+	}
+
 	err = utils.ValidateIngredients(item.Ingredients)
 	if err != nil {
 		http.Error(w, "Ingredient validation failed: "+err.Error(), http.StatusBadRequest)
@@ -79,6 +94,22 @@ func CreateMenuItemHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	for _, variant := range item.Variants {
+		err = repositories.AddVariantToMenu(id, variant)
+		if err != nil {
+			http.Error(w, "Failed to add variant to menu: "+err.Error(), http.StatusInternalServerError)
+			log.Printf("Failed to add %s variant to menu item ID %d: %v", variant.Size, id, err)
+			return
+		}
+	}
+
+	err = repositories.SetMenuItemModifiers(dbConn, id, item.ModifierIDs)
+	if err != nil {
+		http.Error(w, "Failed to add modifiers to menu: "+err.Error(), http.StatusInternalServerError)
+		log.Printf("Failed to add modifiers to menu item ID %d: %v", id, err)
+		return
+	}
+
 	log.Printf("Menu item created successfully with ID: %d", id)
 
 	response := map[string]int{"id": id}
@@ -97,12 +128,41 @@ func DeleteMenuItemHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := repositories.DeleteMenuItem(id)
+	expectedVersion, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	err := repositories.DeleteMenuItem(id, expectedVersion)
+	if err != nil {
+		writeVersionError(w, "Failed to delete menu item: ", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RESTORE -----------------------------------------------------------------------------------------
+func RestoreMenuItemHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/menu/"), "/restore")
+
+	if id == "" {
+		http.Error(w, "ID not found", http.StatusBadRequest)
+		return
+	}
+
+	expectedVersion, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	version, err := repositories.RestoreMenuItem(id, expectedVersion)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to delete menu item: %v", err), http.StatusBadRequest)
+		writeVersionError(w, "Failed to restore menu item: ", err)
 		return
 	}
 
+	w.Header().Set("ETag", formatETag(version))
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -117,6 +177,12 @@ func UpdateMenuItemHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	expectedVersion, ok := requireIfMatch(w, r)
+	if !ok {
+		log.Printf("%s Missing or invalid If-Match for menu item %s", logPrefix, id)
+		return
+	}
+
 	var item models.MenuItem
 
 	err := json.NewDecoder(r.Body).Decode(&item)
@@ -138,12 +204,27 @@ func UpdateMenuItemHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	validSizes := []string{"small", "medium", "large"}
-	if !utils.IsValidSize(validSizes, item.Size) {
+	// Блюдо с вариантами может не иметь собственного размера
+	if !(item.Size == "" && len(item.Variants) > 0) && !utils.IsValidSize(validSizes, item.Size) {
 		http.Error(w, "Invalid size", http.StatusBadRequest)
 		log.Printf("Invalid size: %s", item.Size)
 		return
 	}
 
+	err = utils.ValidateVariants(validSizes, item.Variants)
+	if err != nil {
+		http.Error(w, "Variant validation failed: "+err.Error(), http.StatusBadRequest)
+		log.Printf("Variant validation failed: %v", err)
+		return
+	}
+
+	err = utils.ValidateModifierIDs(item.ModifierIDs)
+	if err != nil {
+		http.Error(w, "Modifier validation failed: "+err.Error(), http.StatusBadRequest)
+		log.Printf("Modifier validation failed: %v", err)
+		return
+	}
+
 	err = utils.ValidateIngredients(item.Ingredients)
 	if err != nil {
 		log.Printf("%s Ingredient validation failed: %v", logPrefix, err)
@@ -152,21 +233,24 @@ func UpdateMenuItemHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("%s Updating menu item ID: %s", logPrefix, id)
-	err = repositories.UpdateMenuItem(id, item)
+	version, err := repositories.UpdateMenuItem(id, item, expectedVersion)
 	if err != nil {
 		log.Printf("%s Failed to update menu item: %v", logPrefix, err)
-		http.Error(w, "Failed to update menu item: "+err.Error(), http.StatusBadRequest)
+		writeVersionError(w, "Failed to update menu item: ", err)
 		return
 	}
 
 	log.Printf("%s Menu item with ID %s updated successfully", logPrefix, id)
+	w.Header().Set("ETag", formatETag(version))
 	w.WriteHeader(http.StatusOK)
 }
 
 // GET --------------------------------------------------------------------------------
 func GetMenuItemsHandler(w http.ResponseWriter, r *http.Request) {
 	// Шаг 1: Получаем данные из базы данных
-	items, err := repositories.GetMenuItems()
+	includeArchived := r.URL.Query().Get("include_archived") == "true"
+	availableOnly := r.URL.Query().Get("available_only") == "true"
+	items, err := repositories.GetMenuItems(includeArchived, availableOnly)
 	if err != nil {
 		http.Error(w, "Не удалось получить элементы меню: "+err.Error(), http.StatusInternalServerError)
 		log.Println("Ошибка при получении элементов меню:", err)
@@ -190,11 +274,16 @@ func GetMenuItemsIDHandler(w http.ResponseWriter, r *http.Request) {
 
 	items, err := repositories.GetMenuItemByID(id)
 	if err != nil {
-		http.Error(w, "Не удалось получить элементы меню: "+err.Error(), http.StatusBadRequest)
+		status := http.StatusBadRequest
+		if errors.Is(err, repositories.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, "Не удалось получить элементы меню: "+err.Error(), status)
 		log.Println("Ошибка при получении элементов меню:", err)
 		return
 	}
 
+	w.Header().Set("ETag", formatETag(items[0].Version))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(items)
 }
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"frappuccino/models"
+	"frappuccino/repositories"
+	"frappuccino/utils"
+	"log"
+	"net/http"
+)
+
+func GetModifiersHandler(w http.ResponseWriter, r *http.Request) {
+	modifiers, err := repositories.GetModifiers()
+	if err != nil {
+		http.Error(w, "Could not get modifiers: "+err.Error(), http.StatusInternalServerError)
+		log.Printf("Error getting modifiers: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(modifiers)
+}
+
+func CreateModifierHandler(w http.ResponseWriter, r *http.Request) {
+	const logPrefix = "[CreateModifierHandler]"
+
+	var modifier models.Modifier
+	err := json.NewDecoder(r.Body).Decode(&modifier)
+	if err != nil {
+		log.Printf("%s Failed to decode JSON: %v", logPrefix, err)
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	if modifier.Code == "" || modifier.Name == "" {
+		http.Error(w, "Code and name are required", http.StatusBadRequest)
+		return
+	}
+	if modifier.PriceDelta < 0 {
+		http.Error(w, "Price delta must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	ingredients := make([]int, 0, len(modifier.Ingredients))
+	for _, ing := range modifier.Ingredients {
+		if ing.Quantity == nil && ing.ReplacesIngredientID == nil {
+			http.Error(w, "Quantity is required for added ingredients", http.StatusBadRequest)
+			return
+		}
+		if ing.Quantity != nil && *ing.Quantity <= 0 {
+			http.Error(w, "Quantity must be greater than 0", http.StatusBadRequest)
+			return
+		}
+		ingredients = append(ingredients, ing.IngredientID)
+		if ing.ReplacesIngredientID != nil {
+			ingredients = append(ingredients, *ing.ReplacesIngredientID)
+		}
+	}
+
+	err = utils.ValidateIngredientIDs(ingredients)
+	if err != nil {
+		log.Printf("%s Ingredient validation failed: %v", logPrefix, err)
+		http.Error(w, "Ingredient validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := repositories.CreateModifier(modifier)
+	if err != nil {
+		log.Printf("%s Failed to create modifier: %v", logPrefix, err)
+		http.Error(w, "Could not create modifier: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("%s Modifier created successfully with ID: %d", logPrefix, id)
+
+	response := map[string]int{"id": id}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
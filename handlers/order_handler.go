@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"frappuccino/models"
 	"frappuccino/repositories"
 	"net/http"
@@ -38,12 +39,37 @@ func CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Простейшая валидация
-	if order.CustomerID == 0 || order.TotalAmount <= 0 {
+	// Простейшая валидация. Сумма считается по позициям, поэтому total_amount не нужен
+	if order.CustomerID == 0 {
 		http.Error(w, "Неверные данные заказа", http.StatusBadRequest)
 		return
 	}
 
+	if err := validateTip(order.TipAmount, order.TipPercent); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if order.RedeemPoints < 0 {
+		http.Error(w, "Количество баллов не может быть отрицательным", http.StatusBadRequest)
+		return
+	}
+	if order.RedeemPoints > 0 {
+		err = repositories.CheckPointsBalance(order.CustomerID, order.RedeemPoints)
+		if err != nil {
+			http.Error(w, "Недостаточно баллов: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if order.PromoCode != "" {
+		err = repositories.CheckPromoCode(order.PromoCode, order.CustomerID)
+		if err != nil {
+			http.Error(w, "Промокод не принят: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	id, err := repositories.CreateOrder(order)
 	if err != nil {
 		http.Error(w, "Ошибка при создании заказа: "+err.Error(), http.StatusInternalServerError)
@@ -94,7 +120,10 @@ func UpdateOrderHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	err = repositories.UpdateOrderStatus(id, data.Status)
-	if err != nil {
+	if errors.Is(err, repositories.ErrConflict) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
 		http.Error(w, "Ошибка при обновлении: "+err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -102,3 +131,81 @@ func UpdateOrderHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"message":"Статус обновлён и история записана"}`))
 }
+
+func validateTip(amount float64, percent *float64) error {
+	if amount < 0 {
+		return errors.New("Чаевые не могут быть отрицательными")
+	}
+	if percent != nil {
+		if *percent < 0 || *percent > 100 {
+			return errors.New("Процент чаевых должен быть от 0 до 100")
+		}
+		if amount != 0 {
+			return errors.New("Укажите либо tip_amount, либо tip_percent")
+		}
+	}
+	return nil
+}
+
+// UpdateOrderTipHandler — PUT /orders/{id}/tip, тело {"tip_amount": 1.5} или {"tip_percent": 10}
+func UpdateOrderTipHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/orders/"), "/tip")
+
+	if id == "" {
+		http.Error(w, "ID не указан", http.StatusBadRequest)
+		return
+	}
+
+	var data struct {
+		TipAmount  float64  `json:"tip_amount"`
+		TipPercent *float64 `json:"tip_percent"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if err != nil {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := validateTip(data.TipAmount, data.TipPercent); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = repositories.SetOrderTip(id, data.TipAmount, data.TipPercent)
+	if err != nil {
+		http.Error(w, "Ошибка при обновлении чаевых: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	order, err := repositories.GetOrderById(id)
+	if err != nil {
+		http.Error(w, "Ошибка при получении заказа: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// GetOrderETAHandler — GET /orders/{id}/eta
+func GetOrderETAHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/orders/"), "/eta")
+
+	if id == "" {
+		http.Error(w, "ID не указан", http.StatusBadRequest)
+		return
+	}
+
+	eta, err := repositories.GetOrderETA(id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Ошибка при оценке времени готовности: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(eta)
+}
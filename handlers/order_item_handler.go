@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"frappuccino/models"
 	"frappuccino/repositories"
+	"math"
 	"net/http"
 	"strings"
 )
@@ -39,12 +41,33 @@ func CreateOrderItemHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if item.OrderID == 0 || item.MenuItemID == 0 || item.Quantity <= 0 || item.PriceAtOrderTime <= 0 {
+	if item.OrderID == 0 || item.MenuItemID == 0 || item.Quantity <= 0 {
 		http.Error(w, "Неверные данные позиции", http.StatusBadRequest)
 		return
 	}
 
-	ok, err := repositories.HasEnoughIngredients(item.MenuItemID, item.Quantity)
+	// Цена берётся из меню (с учётом размера), а не из запроса
+	price, err := repositories.GetOrderItemPrice(item.MenuItemID, item.VariantID)
+	if errors.Is(err, repositories.ErrConflict) {
+		http.Error(w, "Блюдо недоступно: "+err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, "Неверные данные позиции: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Модификаторы должны быть разрешены для блюда; их доплата входит в цену позиции
+	item.Modifiers, err = repositories.ValidateOrderItemModifiers(item.MenuItemID, item.Modifiers)
+	if err != nil {
+		http.Error(w, "Неверные модификаторы: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, m := range item.Modifiers {
+		price += m.PriceDelta * float64(m.Quantity)
+	}
+	item.PriceAtOrderTime = math.Round(price*100) / 100
+
+	ok, err := repositories.HasEnoughIngredients(item)
 	if err != nil {
 		http.Error(w, "Ошибка проверки остатков: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -54,15 +77,13 @@ func CreateOrderItemHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Позиция создаётся вместе со списанием ингредиентов в одной транзакции
 	id, err := repositories.CreateOrderItem(item)
-	if err != nil {
-		http.Error(w, "Ошибка при добавлении позиции: "+err.Error(), http.StatusInternalServerError)
+	if errors.Is(err, repositories.ErrConflict) {
+		http.Error(w, "Недостаточно ингредиентов: "+err.Error(), http.StatusConflict)
 		return
-	}
-
-	err = repositories.DeductIngredients(item.MenuItemID, item.Quantity)
-	if err != nil {
-		http.Error(w, "Ошибка при списании ингредиентов: "+err.Error(), http.StatusInternalServerError)
+	} else if err != nil {
+		http.Error(w, "Ошибка при добавлении позиции: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"frappuccino/models"
+	"frappuccino/repositories"
+	"frappuccino/utils"
+	"net/http"
+)
+
+func GetPromotionsHandler(w http.ResponseWriter, r *http.Request) {
+	promotions, err := repositories.GetPromotions()
+	if err != nil {
+		http.Error(w, "Ошибка при получении акций: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(promotions)
+}
+
+func CreatePromotionHandler(w http.ResponseWriter, r *http.Request) {
+	// По умолчанию акция активна
+	promo := models.Promotion{Active: true}
+
+	err := json.NewDecoder(r.Body).Decode(&promo)
+	if err != nil {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return
+	}
+
+	if promo.Name == "" {
+		http.Error(w, "Название акции обязательно", http.StatusBadRequest)
+		return
+	}
+
+	switch promo.Type {
+	case "percentage":
+		if promo.Value <= 0 || promo.Value > 100 {
+			http.Error(w, "Процент скидки должен быть от 0 до 100", http.StatusBadRequest)
+			return
+		}
+	case "fixed":
+		if promo.Value <= 0 {
+			http.Error(w, "Сумма скидки должна быть больше 0", http.StatusBadRequest)
+			return
+		}
+	case "bogo":
+	default:
+		http.Error(w, "Тип акции: percentage, fixed или bogo", http.StatusBadRequest)
+		return
+	}
+
+	if (promo.HappyHourStart == "") != (promo.HappyHourEnd == "") {
+		http.Error(w, "Для happy hour нужны и начало, и конец", http.StatusBadRequest)
+		return
+	}
+	if promo.HappyHourStart != "" {
+		if err := utils.ValidateClock(promo.HappyHourStart); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := utils.ValidateClock(promo.HappyHourEnd); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if promo.StartsAt != nil && promo.EndsAt != nil && !promo.EndsAt.After(*promo.StartsAt) {
+		http.Error(w, "Окончание акции должно быть позже начала", http.StatusBadRequest)
+		return
+	}
+	if (promo.UsageLimit != nil && *promo.UsageLimit <= 0) || (promo.PerCustomerLimit != nil && *promo.PerCustomerLimit <= 0) {
+		http.Error(w, "Лимиты использования должны быть больше 0", http.StatusBadRequest)
+		return
+	}
+
+	id, err := repositories.CreatePromotion(promo)
+	if err != nil {
+		http.Error(w, "Ошибка при создании акции: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]int{"id": id}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"frappuccino/models"
+	"frappuccino/repositories"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var validPurchaseOrderStatuses = []string{
+	models.PurchaseOrderDraft, models.PurchaseOrderSent, models.PurchaseOrderPartiallyReceived,
+	models.PurchaseOrderReceived, models.PurchaseOrderClosed,
+}
+
+func isValidPurchaseOrderStatus(status string) bool {
+	for _, s := range validPurchaseOrderStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func CreatePurchaseOrderHandler(w http.ResponseWriter, r *http.Request) {
+	var po models.PurchaseOrder
+	err := json.NewDecoder(r.Body).Decode(&po)
+	if err != nil {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return
+	}
+
+	if po.SupplierID <= 0 {
+		http.Error(w, "supplier_id обязателен", http.StatusBadRequest)
+		return
+	}
+	if len(po.Lines) == 0 {
+		http.Error(w, "Заказ должен содержать хотя бы одну строку", http.StatusBadRequest)
+		return
+	}
+	for _, line := range po.Lines {
+		if line.InventoryID <= 0 || line.Packs <= 0 {
+			http.Error(w, "Каждая строка должна содержать inventory_id и packs больше 0", http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := repositories.CreatePurchaseOrder(po)
+	if errors.Is(err, repositories.ErrNotFound) {
+		http.Error(w, "Неверный заказ: "+err.Error(), http.StatusBadRequest)
+		return
+	} else if errors.Is(err, repositories.ErrConflict) {
+		http.Error(w, "Неверный заказ: "+err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, "Ошибка при создании заказа поставщику: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]int{"id": id}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func GetPurchaseOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && !isValidPurchaseOrderStatus(status) {
+		http.Error(w, "Недопустимый статус: "+status, http.StatusBadRequest)
+		return
+	}
+
+	orders, err := repositories.GetPurchaseOrders(status)
+	if err != nil {
+		http.Error(w, "Ошибка при получении заказов поставщикам: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(orders)
+}
+
+func GetPurchaseOrderHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/purchase-orders/")
+	if id == "" {
+		http.Error(w, "ID не указан", http.StatusBadRequest)
+		return
+	}
+
+	po, err := repositories.GetPurchaseOrderByID(id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Ошибка при получении заказа поставщику: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(po)
+}
+
+// PurchaseOrderActionHandler — POST /purchase-orders/{id}/send и /purchase-orders/{id}/close.
+func PurchaseOrderActionHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/purchase-orders/")
+	slash := strings.LastIndex(path, "/")
+	if slash <= 0 {
+		http.Error(w, "ID не указан", http.StatusBadRequest)
+		return
+	}
+	id, action := path[:slash], path[slash+1:]
+
+	var err error
+	switch action {
+	case "send":
+		err = repositories.SendPurchaseOrder(id)
+	case "close":
+		err = repositories.ClosePurchaseOrder(id)
+	default:
+		http.Error(w, "Неизвестное действие: "+action, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeVersionError(w, "Не удалось изменить статус заказа поставщику: ", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReceivePurchaseOrderHandler — POST /purchase-orders/{id}/receive.
+func ReceivePurchaseOrderHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/purchase-orders/"), "/receive")
+	if id == "" {
+		http.Error(w, "ID не указан", http.StatusBadRequest)
+		return
+	}
+
+	var receiving models.PurchaseOrderReceiving
+	if err := json.NewDecoder(r.Body).Decode(&receiving); err != nil {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return
+	}
+	if len(receiving.Lines) == 0 {
+		http.Error(w, "Приёмка должна содержать хотя бы одну строку", http.StatusBadRequest)
+		return
+	}
+	for _, line := range receiving.Lines {
+		if line.LineID <= 0 || line.Quantity <= 0 {
+			http.Error(w, "Каждая строка должна содержать line_id и quantity больше 0", http.StatusBadRequest)
+			return
+		}
+		if line.UnitCost != nil && *line.UnitCost < 0 {
+			http.Error(w, "Цена за единицу не может быть отрицательной", http.StatusBadRequest)
+			return
+		}
+		if line.ExpiresAt != nil && !line.ExpiresAt.After(time.Now()) {
+			http.Error(w, "Срок годности должен быть в будущем", http.StatusBadRequest)
+			return
+		}
+	}
+
+	receipt, err := repositories.ReceivePurchaseOrder(id, receiving)
+	if err != nil {
+		writeVersionError(w, "Не удалось принять заказ поставщику: ", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(receipt)
+}
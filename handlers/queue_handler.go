@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"frappuccino/repositories"
+	"net/http"
+	"strings"
+)
+
+func GetQueueHandler(w http.ResponseWriter, r *http.Request) {
+	queue, err := repositories.GetQueue()
+	if err != nil {
+		http.Error(w, "Ошибка при получении очереди: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(queue)
+}
+
+// ClaimTicketHandler — POST /queue/{orderId}/claim, тело {"staff_id": 1}
+func ClaimTicketHandler(w http.ResponseWriter, r *http.Request) {
+	orderID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/queue/"), "/claim")
+
+	if orderID == "" {
+		http.Error(w, "ID заказа не указан", http.StatusBadRequest)
+		return
+	}
+
+	var data struct {
+		StaffID int `json:"staff_id"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if err != nil || data.StaffID == 0 {
+		http.Error(w, "Неверный JSON или не указан staff_id", http.StatusBadRequest)
+		return
+	}
+
+	err = repositories.ClaimTicket(orderID, data.StaffID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if errors.Is(err, repositories.ErrConflict) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, "Ошибка при взятии заказа: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Заказ взят в работу"}`))
+}
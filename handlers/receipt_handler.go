@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"frappuccino/models"
+	"frappuccino/repositories"
+	"frappuccino/utils"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// GetOrderReceiptHandler — GET /orders/{id}/receipt?format=text|html|escpos&width=42
+func GetOrderReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/orders/"), "/receipt")
+
+	if id == "" {
+		http.Error(w, "ID не указан", http.StatusBadRequest)
+		return
+	}
+
+	width := utils.DefaultReceiptWidth
+	if v := r.URL.Query().Get("width"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < utils.MinReceiptWidth || n > utils.MaxReceiptWidth {
+			http.Error(w, "width должен быть числом от 24 до 64", http.StatusBadRequest)
+			return
+		}
+		width = n
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "text"
+	}
+	if format != "text" && format != "html" && format != "escpos" {
+		http.Error(w, "format должен быть text, html или escpos", http.StatusBadRequest)
+		return
+	}
+
+	receipt, err := repositories.GetReceipt(id)
+	if err != nil {
+		http.Error(w, "Ошибка при получении чека: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch format {
+	case "html":
+		body, err := utils.RenderReceiptHTML(receipt)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(body)
+	case "escpos":
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", "attachment; filename=receipt-"+id+".bin")
+		w.Write(utils.RenderReceiptESCPOS(receipt, width))
+	default:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(utils.RenderReceiptText(receipt, width)))
+	}
+}
+
+func GetPaymentsHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/orders/"), "/payments")
+
+	payments, err := repositories.GetPayments(id)
+	if err != nil {
+		http.Error(w, "Ошибка при получении оплат: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payments)
+}
+
+func CreatePaymentHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/orders/"), "/payments")
+
+	var payment models.Payment
+	err := json.NewDecoder(r.Body).Decode(&payment)
+	if err != nil {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return
+	}
+
+	if payment.Method != "cash" && payment.Method != "card" && payment.Method != "online" {
+		http.Error(w, "Способ оплаты: cash, card или online", http.StatusBadRequest)
+		return
+	}
+	if payment.Amount <= 0 {
+		http.Error(w, "Сумма оплаты должна быть больше 0", http.StatusBadRequest)
+		return
+	}
+
+	paymentID, err := repositories.CreatePayment(id, payment)
+	if err != nil {
+		http.Error(w, "Ошибка при сохранении оплаты: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := map[string]int{"id": paymentID}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"frappuccino/models"
+	"frappuccino/repositories"
+	"frappuccino/utils"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const maxReorderDays = 365
+
+func parseReorderDays(v string) (int, error) {
+	if v == "" {
+		return repositories.DefaultReorderDays, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 || n > maxReorderDays {
+		return 0, fmt.Errorf("days должен быть числом от 1 до %d", maxReorderDays)
+	}
+	return n, nil
+}
+
+func GetReorderSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	days, err := parseReorderDays(r.URL.Query().Get("days"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	suggestions, err := repositories.GetReorderSuggestions(days)
+	if err != nil {
+		http.Error(w, "Ошибка при расчёте дозаказа: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suggestions)
+}
+
+// ExportOrderSheetHandler отдаёт принятые предложения как CSV-лист заказа,
+// сгруппированный по поставщикам. Пустое тело — принять все предложения.
+func ExportOrderSheetHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.OrderSheetRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return
+	}
+
+	days := repositories.DefaultReorderDays
+	if req.Days != 0 {
+		if days, err = parseReorderDays(strconv.Itoa(req.Days)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	suggestions, err := repositories.GetReorderSuggestions(days)
+	if err != nil {
+		http.Error(w, "Ошибка при расчёте дозаказа: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	accepted, err := acceptSuggestions(suggestions, req.Lines)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeCSVHeaders(w, "order-sheet.csv")
+	if err := writeOrderSheet(w, accepted); err != nil {
+		log.Printf("[ExportOrderSheet] %v", err)
+	}
+}
+
+// acceptSuggestions оставляет выбранные строки в порядке предложений
+// и применяет переопределённое число упаковок.
+func acceptSuggestions(suggestions []models.ReorderSuggestion, lines []models.OrderSheetLine) ([]models.ReorderSuggestion, error) {
+	if len(lines) == 0 {
+		return suggestions, nil
+	}
+
+	byID := make(map[int]models.OrderSheetLine, len(lines))
+	for _, line := range lines {
+		if line.Packs < 0 {
+			return nil, fmt.Errorf("ингредиент %d: packs не может быть отрицательным", line.InventoryID)
+		}
+		byID[line.InventoryID] = line
+	}
+
+	var accepted []models.ReorderSuggestion
+	for _, s := range suggestions {
+		line, ok := byID[s.InventoryID]
+		if !ok {
+			continue
+		}
+		delete(byID, s.InventoryID)
+		if line.Packs > 0 {
+			if s.SupplierID == nil {
+				return nil, fmt.Errorf("ингредиент %d: нет поставщика, число упаковок задать нельзя", s.InventoryID)
+			}
+			if err := utils.SetReorderPacks(&s, line.Packs); err != nil {
+				return nil, fmt.Errorf("ингредиент %d: %v", s.InventoryID, err)
+			}
+		}
+		accepted = append(accepted, s)
+	}
+	for id := range byID {
+		return nil, fmt.Errorf("для ингредиента %d нет предложения дозаказа", id)
+	}
+	return accepted, nil
+}
+
+func writeOrderSheet(w io.Writer, suggestions []models.ReorderSuggestion) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"supplier", "supplier_sku", "inventory_id", "name", "packs", "pack_size", "unit",
+		"quantity", "pack_price", "line_total"})
+	for _, s := range suggestions {
+		packs, packSize, packPrice := "", "", ""
+		if s.SupplierID != nil {
+			packs = strconv.Itoa(s.Packs)
+			packSize = s.PackSize.String()
+			packPrice = strconv.FormatFloat(s.PackPrice, 'f', 2, 64)
+		}
+		writer.Write([]string{s.SupplierName, s.SupplierSKU, strconv.Itoa(s.InventoryID), s.Name, packs, packSize,
+			s.Unit, s.SuggestedQuantity.String(), packPrice, strconv.FormatFloat(s.EstimatedCost, 'f', 2, 64)})
+	}
+	writer.Flush()
+	return writer.Error()
+}
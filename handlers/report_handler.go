@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"frappuccino/models"
+	"frappuccino/repositories"
+	"net/http"
+	"time"
+)
+
+// parsePeriod читает ?from=YYYY-MM-DD&to=YYYY-MM-DD. По умолчанию — последние 30 дней,
+// to включительно.
+func parsePeriod(r *http.Request) (time.Time, time.Time, error) {
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, 1)
+	from := to.AddDate(0, 0, -30)
+
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return from, to, fmt.Errorf("from должен быть в формате YYYY-MM-DD")
+		}
+		from = t
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return from, to, fmt.Errorf("to должен быть в формате YYYY-MM-DD")
+		}
+		to = t.AddDate(0, 0, 1)
+	}
+	if !to.After(from) {
+		return from, to, fmt.Errorf("to должен быть не раньше from")
+	}
+
+	return from, to, nil
+}
+
+func GetDiscountReportHandler(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parsePeriod(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := repositories.GetDiscountReport(from, to)
+	if err != nil {
+		http.Error(w, "Ошибка при построении отчёта: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetStatusDurationsHandler — GET /reports/status-durations?group_by=hour|dow|category&from=&to=
+func GetStatusDurationsHandler(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parsePeriod(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	groupBy := r.URL.Query().Get("group_by")
+	if !repositories.IsValidStatusDurationSlice(groupBy) {
+		http.Error(w, "group_by должен быть hour, dow или category", http.StatusBadRequest)
+		return
+	}
+
+	report, err := repositories.GetStatusDurations(from, to, groupBy)
+	if err != nil {
+		http.Error(w, "Ошибка при построении отчёта: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetWasteReportHandler — GET /reports/waste?period=day|week|month&reason=&from=&to=
+func GetWasteReportHandler(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parsePeriod(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	period := r.URL.Query().Get("period")
+	if !repositories.IsValidWastePeriod(period) {
+		http.Error(w, "period должен быть day, week или month", http.StatusBadRequest)
+		return
+	}
+
+	reason := r.URL.Query().Get("reason")
+	if reason != "" && !isValidWasteReason(reason) {
+		http.Error(w, "reason должен быть spoiled, spilled, remake или expired", http.StatusBadRequest)
+		return
+	}
+
+	report, err := repositories.GetWasteReport(from, to, period, reason)
+	if err != nil {
+		http.Error(w, "Ошибка при построении отчёта: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetInventoryValuationHandler — GET /reports/inventory-valuation?method=average|fifo&as_of=YYYY-MM-DD.
+// Без as_of — текущие остатки; с as_of — на конец указанного дня.
+func GetInventoryValuationHandler(w http.ResponseWriter, r *http.Request) {
+	method := r.URL.Query().Get("method")
+	if method == "" {
+		method = models.ValuationAverage
+	}
+	if method != models.ValuationAverage && method != models.ValuationFIFO {
+		http.Error(w, "method должен быть average или fifo", http.StatusBadRequest)
+		return
+	}
+
+	var asOf *time.Time
+	if v := r.URL.Query().Get("as_of"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			http.Error(w, "as_of должен быть в формате YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		if t.After(time.Now()) {
+			http.Error(w, "as_of не может быть в будущем", http.StatusBadRequest)
+			return
+		}
+		asOf = &t
+	}
+
+	valuation, err := repositories.GetInventoryValuation(method, asOf)
+	if err != nil {
+		http.Error(w, "Ошибка при построении отчёта: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(valuation)
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"frappuccino/models"
+	"frappuccino/repositories"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var validStocktakeStatuses = []string{models.StocktakeOpen, models.StocktakeApproved, models.StocktakeCanceled}
+
+func isValidStocktakeStatus(status string) bool {
+	for _, s := range validStocktakeStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateStocktakeHandler — POST /stocktakes. Тело необязательно:
+// {"note": "...", "inventory_ids": [1, 2]} ограничивает пересчёт.
+func CreateStocktakeHandler(w http.ResponseWriter, r *http.Request) {
+	var st models.Stocktake
+	err := json.NewDecoder(r.Body).Decode(&st)
+	if err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return
+	}
+
+	id, err := repositories.CreateStocktake(st)
+	if errors.Is(err, repositories.ErrNotFound) {
+		http.Error(w, "Неверная инвентаризация: "+err.Error(), http.StatusBadRequest)
+		return
+	} else if errors.Is(err, repositories.ErrConflict) {
+		http.Error(w, "Неверная инвентаризация: "+err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, "Ошибка при создании инвентаризации: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]int{"id": id}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func GetStocktakesHandler(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && !isValidStocktakeStatus(status) {
+		http.Error(w, "Недопустимый статус: "+status, http.StatusBadRequest)
+		return
+	}
+
+	stocktakes, err := repositories.GetStocktakes(status)
+	if err != nil {
+		http.Error(w, "Ошибка при получении инвентаризаций: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stocktakes)
+}
+
+// GetStocktakeHandler — GET /stocktakes/{id}: строки с расхождениями в единицах и деньгах.
+func GetStocktakeHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/stocktakes/")
+	if id == "" {
+		http.Error(w, "ID не указан", http.StatusBadRequest)
+		return
+	}
+
+	st, err := repositories.GetStocktakeByID(id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Ошибка при получении инвентаризации: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(st)
+}
+
+// RecordStocktakeCountsHandler — PUT /stocktakes/{id}/counts
+// с телом [{"inventory_id": 1, "counted_quantity": 950}].
+func RecordStocktakeCountsHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/stocktakes/"), "/counts")
+	if id == "" {
+		http.Error(w, "ID не указан", http.StatusBadRequest)
+		return
+	}
+
+	var counts []models.StocktakeCount
+	if err := json.NewDecoder(r.Body).Decode(&counts); err != nil {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return
+	}
+	if len(counts) == 0 {
+		http.Error(w, "Нужно указать хотя бы одну позицию", http.StatusBadRequest)
+		return
+	}
+	for _, c := range counts {
+		if c.InventoryID <= 0 || c.CountedQuantity < 0 {
+			http.Error(w, "Каждая позиция должна содержать inventory_id и counted_quantity не меньше 0", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := repositories.RecordStocktakeCounts(id, counts); err != nil {
+		writeVersionError(w, "Не удалось сохранить пересчёт: ", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// StocktakeActionHandler — POST /stocktakes/{id}/approve и /stocktakes/{id}/cancel.
+func StocktakeActionHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/stocktakes/")
+	slash := strings.LastIndex(path, "/")
+	if slash <= 0 {
+		http.Error(w, "ID не указан", http.StatusBadRequest)
+		return
+	}
+	id, action := path[:slash], path[slash+1:]
+
+	switch action {
+	case "approve":
+		st, err := repositories.ApproveStocktake(id)
+		if err != nil {
+			writeVersionError(w, "Не удалось утвердить инвентаризацию: ", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(st)
+	case "cancel":
+		if err := repositories.CancelStocktake(id); err != nil {
+			writeVersionError(w, "Не удалось отменить инвентаризацию: ", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Неизвестное действие: "+action, http.StatusNotFound)
+	}
+}
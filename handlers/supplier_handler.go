@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"frappuccino/models"
+	"frappuccino/repositories"
+	"net/http"
+	"strings"
+)
+
+func CreateSupplierHandler(w http.ResponseWriter, r *http.Request) {
+	supplier := models.Supplier{Active: true, LeadTimeDays: 1}
+	err := json.NewDecoder(r.Body).Decode(&supplier)
+	if err != nil {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return
+	}
+
+	if supplier.Name == "" {
+		http.Error(w, "Название поставщика обязательно", http.StatusBadRequest)
+		return
+	}
+	if supplier.LeadTimeDays < 0 {
+		http.Error(w, "Срок поставки не может быть отрицательным", http.StatusBadRequest)
+		return
+	}
+	if err := validateSupplierItems(supplier.Items); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := repositories.CreateSupplier(supplier)
+	if err != nil {
+		http.Error(w, "Ошибка при создании поставщика: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := map[string]int{"id": id}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func validateSupplierItems(items []models.SupplierItem) error {
+	seen := make(map[int]bool)
+	for _, item := range items {
+		if item.InventoryID <= 0 {
+			return fmt.Errorf("inventory_id позиции каталога обязателен")
+		}
+		if seen[item.InventoryID] {
+			return fmt.Errorf("ингредиент %d указан в каталоге несколько раз", item.InventoryID)
+		}
+		seen[item.InventoryID] = true
+		if item.PackSize <= 0 {
+			return fmt.Errorf("размер упаковки ингредиента %d должен быть больше 0", item.InventoryID)
+		}
+		if item.PackPrice < 0 {
+			return fmt.Errorf("цена упаковки ингредиента %d не может быть отрицательной", item.InventoryID)
+		}
+	}
+	return nil
+}
+
+func GetSuppliersHandler(w http.ResponseWriter, r *http.Request) {
+	suppliers, err := repositories.GetSuppliers()
+	if err != nil {
+		http.Error(w, "Ошибка при получении поставщиков: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suppliers)
+}
+
+func GetSupplierHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/suppliers/")
+	if id == "" {
+		http.Error(w, "ID не указан", http.StatusBadRequest)
+		return
+	}
+
+	supplier, err := repositories.GetSupplierByID(id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Ошибка при получении поставщика: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(supplier)
+}
+
+// UpdateSupplierItemsHandler — PUT /suppliers/{id}/items, тело — полный каталог.
+func UpdateSupplierItemsHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/suppliers/"), "/items")
+	if id == "" {
+		http.Error(w, "ID не указан", http.StatusBadRequest)
+		return
+	}
+
+	var items []models.SupplierItem
+	if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return
+	}
+	if err := validateSupplierItems(items); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := repositories.SetSupplierItems(id, items)
+	if errors.Is(err, repositories.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Ошибка при обновлении каталога: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"frappuccino/models"
+	"frappuccino/repositories"
+	"net/http"
+)
+
+func GetTaxRatesHandler(w http.ResponseWriter, r *http.Request) {
+	rates, err := repositories.GetTaxRates()
+	if err != nil {
+		http.Error(w, "Ошибка при получении налоговых ставок: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rates)
+}
+
+func CreateTaxRateHandler(w http.ResponseWriter, r *http.Request) {
+	rate := models.TaxRate{Active: true}
+
+	err := json.NewDecoder(r.Body).Decode(&rate)
+	if err != nil {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return
+	}
+
+	if rate.Name == "" {
+		http.Error(w, "Название ставки обязательно", http.StatusBadRequest)
+		return
+	}
+	if rate.Rate < 0 || rate.Rate > 100 {
+		http.Error(w, "Ставка должна быть от 0 до 100 процентов", http.StatusBadRequest)
+		return
+	}
+
+	id, err := repositories.CreateTaxRate(rate)
+	if err != nil {
+		http.Error(w, "Ошибка при создании налоговой ставки: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]int{"id": id}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
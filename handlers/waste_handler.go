@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"encoding/json"
+	"frappuccino/models"
+	"frappuccino/repositories"
+	"net/http"
+	"strings"
+)
+
+var validWasteReasons = []string{models.WasteSpoiled, models.WasteSpilled, models.WasteRemake, models.WasteExpired}
+
+func isValidWasteReason(reason string) bool {
+	for _, r := range validWasteReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordWasteHandler — POST /inventory/{id}/waste
+func RecordWasteHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/inventory/"), "/waste")
+	if id == "" {
+		http.Error(w, "ID не указан", http.StatusBadRequest)
+		return
+	}
+
+	var entry models.WasteEntry
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return
+	}
+	if entry.Quantity <= 0 {
+		http.Error(w, "Количество должно быть больше 0", http.StatusBadRequest)
+		return
+	}
+	if !isValidWasteReason(entry.Reason) {
+		http.Error(w, "reason должен быть spoiled, spilled, remake или expired", http.StatusBadRequest)
+		return
+	}
+	entry.LotID = nil
+
+	created, err := repositories.RecordWaste(id, entry)
+	if err != nil {
+		writeVersionError(w, "Не удалось списать ингредиент: ", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
@@ -4,6 +4,11 @@ CREATE TYPE payment_method AS ENUM ('cash', 'card', 'online');
 CREATE TYPE staff_role AS ENUM ('barista', 'cashier', 'manager');
 CREATE TYPE item_size AS ENUM ('small', 'medium', 'large');
 CREATE TYPE unit_type AS ENUM ('grams', 'ml', 'pcs');
+CREATE TYPE promotion_type AS ENUM ('percentage', 'fixed', 'bogo');
+CREATE TYPE purchase_order_status AS ENUM ('draft', 'sent', 'partially_received', 'received', 'closed');
+CREATE TYPE waste_reason AS ENUM ('spoiled', 'spilled', 'remake', 'expired');
+CREATE TYPE stocktake_status AS ENUM ('open', 'approved', 'canceled');
+CREATE TYPE loyalty_reason AS ENUM ('earn', 'redeem', 'reversal');
 
 -- 2. Customers Table
 CREATE TABLE customers (
@@ -12,13 +17,30 @@ CREATE TABLE customers (
     preferences JSONB
 );
 
+-- 2a. Staff
+CREATE TABLE staff (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    role staff_role NOT NULL
+);
+
 -- 3. Orders Table
 CREATE TABLE orders (
     id SERIAL PRIMARY KEY,
     customer_id INTEGER REFERENCES customers(id),
     status order_status DEFAULT 'pending',
     special_instructions JSONB,
+    promo_code TEXT,
+    subtotal NUMERIC(10,2) NOT NULL DEFAULT 0,
+    discount_total NUMERIC(10,2) NOT NULL DEFAULT 0,
+    tax_total NUMERIC(10,2) NOT NULL DEFAULT 0,
+    tip_amount NUMERIC(10,2) NOT NULL DEFAULT 0,
+    tip_percent NUMERIC(5,2),
     total_amount NUMERIC(10,2),
+    assigned_staff_id INTEGER REFERENCES staff(id),
+    claimed_at TIMESTAMPTZ,
+    redeem_points INTEGER NOT NULL DEFAULT 0 CHECK (redeem_points >= 0),
+    points_redeemed INTEGER NOT NULL DEFAULT 0,
     order_date TIMESTAMPTZ DEFAULT NOW()
 );
 
@@ -40,7 +62,20 @@ CREATE TABLE menu_items (
     allergens TEXT[],
     customization_options JSONB,
     size item_size,
-    metadata JSONB
+    metadata JSONB,
+    version INTEGER NOT NULL DEFAULT 1,
+    archived_at TIMESTAMPTZ
+);
+
+-- 5a. Menu Item Variants (size with its own price and recipe; removed sizes are archived, orders keep them)
+CREATE TABLE menu_item_variants (
+    id SERIAL PRIMARY KEY,
+    menu_item_id INTEGER REFERENCES menu_items(id) ON DELETE CASCADE,
+    size item_size NOT NULL,
+    price NUMERIC(10,2) NOT NULL,
+    recipe_multiplier NUMERIC(18,6),
+    archived_at TIMESTAMPTZ,
+    UNIQUE (menu_item_id, size)
 );
 
 -- 6. Order Items
@@ -48,6 +83,7 @@ CREATE TABLE order_items (
     id SERIAL PRIMARY KEY,
     order_id INTEGER REFERENCES orders(id) ON DELETE CASCADE,
     menu_item_id INTEGER REFERENCES menu_items(id),
+    variant_id INTEGER REFERENCES menu_item_variants(id) ON DELETE SET NULL,
     quantity INTEGER NOT NULL,
     price_at_order_time NUMERIC(10,2) NOT NULL,
     customization JSONB
@@ -57,10 +93,14 @@ CREATE TABLE order_items (
 CREATE TABLE inventory (
     id SERIAL PRIMARY KEY,
     name TEXT NOT NULL,
-    quantity INTEGER NOT NULL,
+    quantity NUMERIC(18,6) NOT NULL,
     unit unit_type,
-    price_per_unit NUMERIC(10,2),
-    last_updated TIMESTAMPTZ DEFAULT NOW()
+    price_per_unit NUMERIC(12,4),
+    last_updated TIMESTAMPTZ DEFAULT NOW(),
+    version INTEGER NOT NULL DEFAULT 1,
+    archived_at TIMESTAMPTZ,
+    par_level NUMERIC(18,6) CHECK (par_level >= 0),
+    lead_time_days INTEGER CHECK (lead_time_days >= 0)
 );
 
 -- 8. Menu Item Ingredients (Junction)
@@ -68,7 +108,142 @@ CREATE TABLE menu_item_ingredients (
     id SERIAL PRIMARY KEY,
     menu_item_id INTEGER REFERENCES menu_items(id) ON DELETE CASCADE,
     ingredient_id INTEGER REFERENCES inventory(id),
-    quantity_required INTEGER NOT NULL
+    quantity_required NUMERIC(18,6) NOT NULL CHECK (quantity_required > 0)
+);
+
+-- 8a. Variant Ingredients (own recipe of a size, overrides the base recipe)
+CREATE TABLE menu_item_variant_ingredients (
+    id SERIAL PRIMARY KEY,
+    variant_id INTEGER REFERENCES menu_item_variants(id) ON DELETE CASCADE,
+    ingredient_id INTEGER REFERENCES inventory(id),
+    quantity_required NUMERIC(18,6) NOT NULL CHECK (quantity_required > 0)
+);
+
+-- 8b. Modifiers (priced customizations that change the recipe)
+CREATE TABLE modifiers (
+    id SERIAL PRIMARY KEY,
+    code TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    price_delta NUMERIC(10,2) NOT NULL DEFAULT 0
+);
+
+CREATE TABLE modifier_ingredients (
+    id SERIAL PRIMARY KEY,
+    modifier_id INTEGER REFERENCES modifiers(id) ON DELETE CASCADE,
+    ingredient_id INTEGER REFERENCES inventory(id),
+    quantity NUMERIC(18,6),
+    replaces_ingredient_id INTEGER REFERENCES inventory(id),
+    CHECK (quantity IS NOT NULL OR replaces_ingredient_id IS NOT NULL)
+);
+
+CREATE TABLE menu_item_modifiers (
+    menu_item_id INTEGER REFERENCES menu_items(id) ON DELETE CASCADE,
+    modifier_id INTEGER REFERENCES modifiers(id) ON DELETE CASCADE,
+    PRIMARY KEY (menu_item_id, modifier_id)
+);
+
+CREATE TABLE order_item_modifiers (
+    id SERIAL PRIMARY KEY,
+    order_item_id INTEGER REFERENCES order_items(id) ON DELETE CASCADE,
+    modifier_id INTEGER REFERENCES modifiers(id),
+    quantity INTEGER NOT NULL DEFAULT 1,
+    price_delta NUMERIC(10,2) NOT NULL
+);
+
+-- 8c. Promotions
+CREATE TABLE promotions (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    code TEXT UNIQUE,
+    type promotion_type NOT NULL,
+    value NUMERIC(10,2) NOT NULL DEFAULT 0,
+    category TEXT,
+    starts_at TIMESTAMPTZ,
+    ends_at TIMESTAMPTZ,
+    happy_hour_start TIME,
+    happy_hour_end TIME,
+    usage_limit INTEGER,
+    per_customer_limit INTEGER,
+    active BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+CREATE TABLE order_discounts (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER REFERENCES orders(id) ON DELETE CASCADE,
+    promotion_id INTEGER REFERENCES promotions(id),
+    points INTEGER,
+    description TEXT,
+    amount NUMERIC(10,2) NOT NULL
+);
+
+-- 8d. Taxes
+CREATE TABLE tax_rates (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    rate NUMERIC(6,3) NOT NULL,
+    category TEXT,
+    inclusive BOOLEAN NOT NULL DEFAULT FALSE,
+    active BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+CREATE TABLE order_tax_lines (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER REFERENCES orders(id) ON DELETE CASCADE,
+    tax_rate_id INTEGER REFERENCES tax_rates(id),
+    name TEXT NOT NULL,
+    rate NUMERIC(6,3) NOT NULL,
+    inclusive BOOLEAN NOT NULL,
+    taxable_amount NUMERIC(10,2) NOT NULL,
+    amount NUMERIC(10,2) NOT NULL
+);
+
+-- 8e. Loyalty (rules without category give points for the whole net spend,
+-- rules with a category add bonus points; tiers multiply earned points)
+CREATE TABLE loyalty_rules (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    category TEXT,
+    points_per_unit NUMERIC(10,2) NOT NULL CHECK (points_per_unit >= 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+CREATE TABLE loyalty_tiers (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    min_spend NUMERIC(10,2) NOT NULL UNIQUE CHECK (min_spend >= 0),
+    points_multiplier NUMERIC(5,2) NOT NULL DEFAULT 1 CHECK (points_multiplier > 0)
+);
+
+CREATE TABLE loyalty_ledger (
+    id SERIAL PRIMARY KEY,
+    customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    order_id INTEGER REFERENCES orders(id) ON DELETE SET NULL,
+    points INTEGER NOT NULL,
+    reason loyalty_reason NOT NULL,
+    note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- 8f. Payments
+CREATE TABLE payments (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER REFERENCES orders(id) ON DELETE CASCADE,
+    method payment_method NOT NULL,
+    amount NUMERIC(10,2) NOT NULL,
+    paid_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- 8g. Idempotency Keys (stored responses of POST /orders and POST /order-items)
+CREATE TABLE idempotency_keys (
+    scope TEXT NOT NULL,
+    key TEXT NOT NULL,
+    request_hash TEXT NOT NULL,
+    status_code INTEGER,
+    content_type TEXT,
+    response_body BYTEA,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (scope, key)
 );
 
 -- 9. Price History
@@ -83,16 +258,150 @@ CREATE TABLE price_history (
 CREATE TABLE inventory_transactions (
     id SERIAL PRIMARY KEY,
     inventory_id INTEGER REFERENCES inventory(id) ON DELETE CASCADE,
-    change_amount INTEGER NOT NULL,
+    change_amount NUMERIC(18,6) NOT NULL,
     transaction_date TIMESTAMPTZ DEFAULT NOW(),
-    reason TEXT
+    reason TEXT,
+    unit_cost NUMERIC(12,4),
+    reference_type TEXT,
+    reference_id INTEGER
+);
+
+-- 10a. Suppliers and their catalogs (pack_size is in the inventory item's unit)
+CREATE TABLE suppliers (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    contact_name TEXT,
+    phone TEXT,
+    email TEXT,
+    lead_time_days INTEGER NOT NULL DEFAULT 1 CHECK (lead_time_days >= 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+CREATE TABLE supplier_items (
+    id SERIAL PRIMARY KEY,
+    supplier_id INTEGER NOT NULL REFERENCES suppliers(id) ON DELETE CASCADE,
+    inventory_id INTEGER NOT NULL REFERENCES inventory(id) ON DELETE CASCADE,
+    supplier_sku TEXT,
+    pack_size NUMERIC(18,6) NOT NULL CHECK (pack_size > 0),
+    pack_price NUMERIC(12,4) NOT NULL CHECK (pack_price >= 0),
+    UNIQUE (supplier_id, inventory_id)
+);
+
+-- 10b. Purchase Orders
+CREATE TABLE purchase_orders (
+    id SERIAL PRIMARY KEY,
+    supplier_id INTEGER NOT NULL REFERENCES suppliers(id),
+    status purchase_order_status NOT NULL DEFAULT 'draft',
+    note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMPTZ,
+    expected_at TIMESTAMPTZ,
+    closed_at TIMESTAMPTZ
+);
+
+CREATE TABLE purchase_order_lines (
+    id SERIAL PRIMARY KEY,
+    purchase_order_id INTEGER NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    inventory_id INTEGER NOT NULL REFERENCES inventory(id),
+    packs INTEGER NOT NULL CHECK (packs > 0),
+    pack_size NUMERIC(18,6) NOT NULL,
+    pack_price NUMERIC(12,4) NOT NULL,
+    received_quantity NUMERIC(18,6) NOT NULL DEFAULT 0
+);
+
+-- 10c. Goods Receipts (restock documents, optionally against a purchase order)
+CREATE TABLE goods_receipts (
+    id SERIAL PRIMARY KEY,
+    supplier TEXT,
+    reference TEXT,
+    note TEXT,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    purchase_order_id INTEGER REFERENCES purchase_orders(id)
+);
+
+CREATE TABLE goods_receipt_lines (
+    id SERIAL PRIMARY KEY,
+    receipt_id INTEGER NOT NULL REFERENCES goods_receipts(id) ON DELETE CASCADE,
+    inventory_id INTEGER NOT NULL REFERENCES inventory(id),
+    quantity NUMERIC(18,6) NOT NULL CHECK (quantity > 0),
+    unit_cost NUMERIC(12,4) NOT NULL CHECK (unit_cost >= 0),
+    expires_at TIMESTAMPTZ,
+    purchase_order_line_id INTEGER REFERENCES purchase_order_lines(id)
+);
+
+-- 10d. Inventory Lots (stock broken down by receipt; quantity is what is left of the lot)
+CREATE TABLE inventory_lots (
+    id SERIAL PRIMARY KEY,
+    inventory_id INTEGER NOT NULL REFERENCES inventory(id) ON DELETE CASCADE,
+    quantity NUMERIC(18,6) NOT NULL CHECK (quantity >= 0),
+    initial_quantity NUMERIC(18,6) NOT NULL,
+    unit_cost NUMERIC(12,4),
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ,
+    goods_receipt_line_id INTEGER REFERENCES goods_receipt_lines(id),
+    written_off_at TIMESTAMPTZ
+);
+
+-- 10e. Waste (stock lost without a sale; expired lots are written off here too)
+CREATE TABLE waste_entries (
+    id SERIAL PRIMARY KEY,
+    inventory_id INTEGER NOT NULL REFERENCES inventory(id) ON DELETE CASCADE,
+    quantity NUMERIC(18,6) NOT NULL CHECK (quantity > 0),
+    reason waste_reason NOT NULL,
+    note TEXT,
+    unit_cost NUMERIC(12,4),
+    lot_id INTEGER REFERENCES inventory_lots(id),
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- 10f. Stocktakes (system_quantity and unit_cost are a snapshot taken when the count starts)
+CREATE TABLE stocktakes (
+    id SERIAL PRIMARY KEY,
+    status stocktake_status NOT NULL DEFAULT 'open',
+    note TEXT,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    approved_at TIMESTAMPTZ,
+    canceled_at TIMESTAMPTZ
+);
+
+CREATE TABLE stocktake_lines (
+    id SERIAL PRIMARY KEY,
+    stocktake_id INTEGER NOT NULL REFERENCES stocktakes(id) ON DELETE CASCADE,
+    inventory_id INTEGER NOT NULL REFERENCES inventory(id) ON DELETE CASCADE,
+    system_quantity NUMERIC(18,6) NOT NULL,
+    unit_cost NUMERIC(12,4),
+    counted_quantity NUMERIC(18,6) CHECK (counted_quantity >= 0),
+    counted_at TIMESTAMPTZ,
+    expected_quantity NUMERIC(18,6), -- учётный остаток в момент пересчёта
+    UNIQUE (stocktake_id, inventory_id)
 );
 
 -- 11. Indexes
 CREATE INDEX idx_orders_customer_id ON orders(customer_id);
+CREATE INDEX idx_order_status_history_order_id ON order_status_history(order_id);
+CREATE INDEX idx_order_status_history_changed_at ON order_status_history(changed_at);
+CREATE INDEX idx_orders_queue ON orders(order_date, id) WHERE status IN ('pending', 'preparing');
 CREATE INDEX idx_order_items_order_id ON order_items(order_id);
 CREATE INDEX idx_menu_items_search ON menu_items USING gin (to_tsvector('english', name || ' ' || description));
 CREATE INDEX idx_inventory_name ON inventory(name);
+CREATE INDEX idx_menu_item_variants_menu_item_id ON menu_item_variants(menu_item_id);
+CREATE INDEX idx_order_item_modifiers_order_item_id ON order_item_modifiers(order_item_id);
+CREATE INDEX idx_order_discounts_order_id ON order_discounts(order_id);
+CREATE INDEX idx_order_discounts_promotion_id ON order_discounts(promotion_id);
+CREATE INDEX idx_order_tax_lines_order_id ON order_tax_lines(order_id);
+CREATE INDEX idx_payments_order_id ON payments(order_id);
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+CREATE INDEX idx_inventory_transactions_inventory_id ON inventory_transactions(inventory_id, transaction_date);
+CREATE INDEX idx_goods_receipt_lines_receipt_id ON goods_receipt_lines(receipt_id);
+CREATE INDEX idx_supplier_items_inventory_id ON supplier_items(inventory_id);
+CREATE INDEX idx_purchase_orders_status ON purchase_orders(status);
+CREATE INDEX idx_purchase_order_lines_purchase_order_id ON purchase_order_lines(purchase_order_id);
+CREATE INDEX idx_inventory_lots_open ON inventory_lots(inventory_id, received_at, id) WHERE quantity > 0;
+CREATE INDEX idx_inventory_lots_expires_at ON inventory_lots(expires_at) WHERE quantity > 0;
+CREATE INDEX idx_waste_entries_recorded_at ON waste_entries(recorded_at);
+CREATE INDEX idx_loyalty_ledger_customer_id ON loyalty_ledger(customer_id, created_at);
+CREATE UNIQUE INDEX idx_loyalty_ledger_order_earn ON loyalty_ledger(order_id) WHERE reason = 'earn';
+CREATE UNIQUE INDEX idx_stocktakes_single_open ON stocktakes((TRUE)) WHERE status = 'open';
 
 -- 12. Mock Data
 
@@ -102,6 +411,12 @@ INSERT INTO customers (name, preferences) VALUES
 ('Bob Smith', '{"allergy": "nuts"}'),
 ('Charlie Green', '{}');
 
+-- Staff
+INSERT INTO staff (name, role) VALUES
+('Dana', 'barista'),
+('Eli', 'barista'),
+('Fran', 'manager');
+
 -- Menu Items
 INSERT INTO menu_items (name, description, price, category, allergens, customization_options, size, metadata) VALUES
 ('Latte', 'Classic milk coffee', 4.50, ARRAY['coffee', 'hot'], ARRAY['milk'], '{"syrup": "vanilla"}', 'medium', '{"season": "winter"}'),
@@ -109,12 +424,20 @@ INSERT INTO menu_items (name, description, price, category, allergens, customiza
 ('Muffin', 'Chocolate muffin', 2.00, ARRAY['dessert'], ARRAY['gluten', 'eggs'], '{}', NULL, '{}');
 
 -- Inventory
-INSERT INTO inventory (name, quantity, unit, price_per_unit) VALUES
-('Coffee Beans', 10000, 'grams', 0.05),
-('Milk', 5000, 'ml', 0.03),
-('Chocolate', 2000, 'grams', 0.10),
-('Flour', 3000, 'grams', 0.02),
-('Eggs', 200, 'pcs', 0.15);
+INSERT INTO inventory (name, quantity, unit, price_per_unit, par_level, lead_time_days) VALUES
+('Coffee Beans', 10000, 'grams', 0.05, 3000, NULL),
+('Milk', 5000, 'ml', 0.03, 4000, 1),
+('Chocolate', 2000, 'grams', 0.10, NULL, NULL),
+('Flour', 3000, 'grams', 0.02, NULL, NULL),
+('Eggs', 200, 'pcs', 0.15, NULL, NULL),
+('Oat Milk', 3000, 'ml', 0.05, 2000, NULL),
+('Caramel Syrup', 1000, 'ml', 0.04, NULL, NULL);
+
+-- Opening lots: all seeded stock as one lot per ingredient; milk is perishable
+INSERT INTO inventory_lots (inventory_id, quantity, initial_quantity, unit_cost, received_at, expires_at)
+SELECT id, quantity, quantity, price_per_unit, NOW() - INTERVAL '2 days',
+    CASE WHEN name IN ('Milk', 'Oat Milk') THEN NOW() + INTERVAL '5 days' END
+FROM inventory;
 
 -- Menu Item Ingredients
 INSERT INTO menu_item_ingredients (menu_item_id, ingredient_id, quantity_required) VALUES
@@ -125,17 +448,75 @@ INSERT INTO menu_item_ingredients (menu_item_id, ingredient_id, quantity_require
 (3, 5, 2),
 (3, 3, 50);
 
+-- Menu Item Variants
+INSERT INTO menu_item_variants (menu_item_id, size, price, recipe_multiplier) VALUES
+(1, 'small', 3.80, 0.75),
+(1, 'medium', 4.50, 1),
+(1, 'large', 5.20, 1.5),
+(2, 'small', 3.00, NULL),
+(2, 'medium', 3.80, NULL);
+
+-- Variant Ingredients (double espresso)
+INSERT INTO menu_item_variant_ingredients (variant_id, ingredient_id, quantity_required) VALUES
+(5, 1, 160);
+
+-- Modifiers
+INSERT INTO modifiers (code, name, price_delta) VALUES
+('extra_shot', 'Extra shot', 0.50),
+('oat_milk', 'Oat milk', 0.40),
+('caramel_syrup', 'Caramel syrup', 0.30);
+
+INSERT INTO modifier_ingredients (modifier_id, ingredient_id, quantity, replaces_ingredient_id) VALUES
+(1, 1, 80, NULL),
+(2, 6, NULL, 2),
+(3, 7, 20, NULL);
+
+INSERT INTO menu_item_modifiers (menu_item_id, modifier_id) VALUES
+(1, 1),
+(1, 2),
+(1, 3),
+(2, 1);
+
+-- Promotions
+INSERT INTO promotions (name, code, type, value, category, happy_hour_start, happy_hour_end, usage_limit, per_customer_limit) VALUES
+('Happy hour: 20% off coffee', NULL, 'percentage', 20, 'coffee', '15:00', '17:00', NULL, NULL),
+('Welcome 1.00 off', 'WELCOME', 'fixed', 1.00, NULL, NULL, NULL, 500, 1),
+('Dessert BOGO', 'SWEET2', 'bogo', 0, 'dessert', NULL, NULL, NULL, NULL);
+
+-- Tax Rates
+INSERT INTO tax_rates (name, rate, category, inclusive) VALUES
+('Sales tax', 8.000, NULL, FALSE),
+('Reduced rate (food)', 5.000, 'dessert', FALSE);
+
+-- Loyalty
+INSERT INTO loyalty_rules (name, category, points_per_unit) VALUES
+('1 point per 1.00 spent', NULL, 1),
+('Double points on coffee', 'coffee', 1);
+
+INSERT INTO loyalty_tiers (name, min_spend, points_multiplier) VALUES
+('Bronze', 0, 1),
+('Silver', 100, 1.25),
+('Gold', 300, 1.5);
+
 -- Orders
-INSERT INTO orders (customer_id, status, special_instructions, total_amount, order_date) VALUES
-(1, 'completed', '{"extra_shot": true}', 9.50, NOW() - INTERVAL '2 days'),
-(2, 'preparing', '{}', 5.00, NOW()),
-(3, 'pending', '{"no_milk": true}', 3.00, NOW());
+INSERT INTO orders (customer_id, status, special_instructions, subtotal, total_amount, order_date) VALUES
+(1, 'completed', '{"extra_shot": true}', 9.00, 9.00, NOW() - INTERVAL '2 days'),
+(2, 'preparing', '{}', 3.00, 3.00, NOW()),
+(3, 'pending', '{"no_milk": true}', 3.00, 3.00, NOW());
 
 -- Order Items
-INSERT INTO order_items (order_id, menu_item_id, quantity, price_at_order_time, customization) VALUES
-(1, 1, 2, 4.50, '{"syrup": "caramel"}'),
-(2, 2, 1, 3.00, '{}'),
-(3, 2, 1, 3.00, '{}');
+INSERT INTO order_items (order_id, menu_item_id, variant_id, quantity, price_at_order_time, customization) VALUES
+(1, 1, 2, 2, 4.50, '{"syrup": "caramel"}'),
+(2, 2, 4, 1, 3.00, '{}'),
+(3, 2, 4, 1, 3.00, '{}');
+
+-- Payments
+INSERT INTO payments (order_id, method, amount, paid_at) VALUES
+(1, 'card', 9.00, NOW() - INTERVAL '2 days');
+
+-- Points earned by the completed order
+INSERT INTO loyalty_ledger (customer_id, order_id, points, reason, note, created_at) VALUES
+(1, 1, 18, 'earn', 'Order #1', NOW() - INTERVAL '2 days');
 
 -- Order Status History
 INSERT INTO order_status_history (order_id, status, changed_at) VALUES
@@ -151,7 +532,25 @@ INSERT INTO price_history (menu_item_id, price, changed_at) VALUES
 (2, 3.00, NOW() - INTERVAL '3 months');
 
 -- Inventory Transactions
-INSERT INTO inventory_transactions (inventory_id, change_amount, transaction_date, reason) VALUES
-(1, -200, NOW() - INTERVAL '1 day', 'Order #1'),
-(2, -200, NOW() - INTERVAL '1 day', 'Order #1'),
-(4, -150, NOW() - INTERVAL '2 days', 'Order #3');
+INSERT INTO inventory_transactions (inventory_id, change_amount, transaction_date, reason, unit_cost, reference_type, reference_id) VALUES
+(1, -200, NOW() - INTERVAL '1 day', 'Order #1', 0.05, 'order', 1),
+(2, -200, NOW() - INTERVAL '1 day', 'Order #1', 0.03, 'order', 1),
+(4, -150, NOW() - INTERVAL '2 days', 'Order #3', 0.02, 'order', 3);
+
+-- Opening balances, so that replaying the ledger reproduces the seeded stock
+INSERT INTO inventory_transactions (inventory_id, change_amount, transaction_date, reason, unit_cost, reference_type)
+SELECT i.id, i.quantity - COALESCE(SUM(t.change_amount), 0), NOW() - INTERVAL '30 days', 'Opening balance', i.price_per_unit, 'opening'
+FROM inventory i
+LEFT JOIN inventory_transactions t ON t.inventory_id = i.id
+GROUP BY i.id, i.quantity, i.price_per_unit;
+
+-- Suppliers
+INSERT INTO suppliers (name, contact_name, phone, email, lead_time_days) VALUES
+('Bean Brothers Roastery', 'Sam Ortiz', '+1-555-0101', 'orders@beanbrothers.example', 3),
+('Valley Dairy', 'Kim Lee', '+1-555-0102', 'sales@valleydairy.example', 1);
+
+INSERT INTO supplier_items (supplier_id, inventory_id, supplier_sku, pack_size, pack_price) VALUES
+(1, 1, 'BB-ESP-1KG', 1000, 45.00),
+(1, 3, 'BB-CHOC-500', 500, 48.00),
+(2, 2, 'VD-MILK-1L', 1000, 28.00),
+(2, 6, 'VD-OAT-1L', 1000, 48.00);
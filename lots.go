@@ -0,0 +1,39 @@
+package main
+
+import (
+	"frappuccino/repositories"
+	"log"
+	"os"
+	"time"
+)
+
+// lotWriteOffInterval — как часто списывать просроченные партии. Задаётся
+// переменной окружения LOT_WRITE_OFF_INTERVAL (time.ParseDuration), по умолчанию 1h.
+func lotWriteOffInterval() time.Duration {
+	if v := os.Getenv("LOT_WRITE_OFF_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err == nil && interval > 0 {
+			return interval
+		}
+		log.Printf("[LotWriteOff] Invalid LOT_WRITE_OFF_INTERVAL %q, using 1h", v)
+	}
+	return time.Hour
+}
+
+// runLotWriteOff периодически списывает просроченные партии в журнал.
+// Заказ списывает просрочку по своим ингредиентам и сам, так что пропуск
+// запуска ни на что не влияет, кроме отчётов.
+func runLotWriteOff() {
+	ticker := time.NewTicker(lotWriteOffInterval())
+	defer ticker.Stop()
+
+	for {
+		n, err := repositories.WriteOffExpiredLots()
+		if err != nil {
+			log.Printf("[LotWriteOff] %v", err)
+		} else if n > 0 {
+			log.Printf("[LotWriteOff] Written off %d expired lots", n)
+		}
+		<-ticker.C
+	}
+}
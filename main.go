@@ -4,9 +4,17 @@ package main
 import (
 	"frappuccino/router"
 	"log"
+	"os"
 )
 
 func main() {
+	// Подкоманды import/export работают без сервера
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(os.Args[1:]))
+	}
+
+	go runLotWriteOff()
+
 	// Настроим маршруты
 	router.SetupRouter()
 
@@ -0,0 +1,14 @@
+package models
+
+// CSVRowError — ошибка в строке CSV. Row — номер строки файла (заголовок — строка 1).
+type CSVRowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// CSVImportResult — итог импорта. Если есть ошибки, ничего не загружается.
+type CSVImportResult struct {
+	Imported int           `json:"imported"`
+	Errors   []CSVRowError `json:"errors,omitempty"`
+}
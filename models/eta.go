@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// OrderETA — оценка готовности заказа. Basis показывает, на чём основана
+// оценка времени приготовления: menu_item, global или default; WaitBasis —
+// оценка ожидания в очереди: history (наблюдаемые pending→preparing) или queue.
+type OrderETA struct {
+	OrderID              int        `json:"order_id"`
+	Status               string     `json:"status"`
+	OrdersAhead          int        `json:"orders_ahead"`
+	Stations             int        `json:"stations"`
+	EstimatedWaitSeconds int        `json:"estimated_wait_seconds"`
+	EstimatedPrepSeconds int        `json:"estimated_prep_seconds"`
+	EstimatedReadyAt     *time.Time `json:"estimated_ready_at,omitempty"`
+	Basis                string     `json:"basis"`
+	Samples              int        `json:"samples"`
+	WaitBasis            string     `json:"wait_basis,omitempty"`
+	WaitSamples          int        `json:"wait_samples,omitempty"`
+}
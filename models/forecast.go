@@ -0,0 +1,22 @@
+package models
+
+// IngredientForecast — прогноз расхода ингредиента на Days дней вперёд.
+// StockOutDate — первый день, к концу которого прогнозный расход превысит
+// текущий остаток; пусто, если остатка хватает на весь горизонт.
+type IngredientForecast struct {
+	InventoryID          int           `json:"inventory_id"`
+	Name                 string        `json:"name"`
+	Unit                 string        `json:"unit"`
+	Quantity             Quantity      `json:"quantity"`
+	AvgDailyUsage        Quantity      `json:"avg_daily_usage"`
+	ProjectedConsumption Quantity      `json:"projected_consumption"`
+	ProjectedRemaining   Quantity      `json:"projected_remaining"`
+	StockOutDate         *string       `json:"stock_out_date"`
+	DaysUntilStockOut    *int          `json:"days_until_stock_out"`
+	Daily                []ForecastDay `json:"daily"`
+}
+
+type ForecastDay struct {
+	Date     string   `json:"date"`
+	Quantity Quantity `json:"quantity"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// GoodsReceipt — документ поступления товара. Каждая строка увеличивает остаток
+// и пересчитывает price_per_unit ингредиента как средневзвешенную цену.
+type GoodsReceipt struct {
+	ID         int                `json:"id"`
+	Supplier   string             `json:"supplier,omitempty"`
+	Reference  string             `json:"reference,omitempty"`
+	Note       string             `json:"note,omitempty"`
+	ReceivedAt time.Time          `json:"received_at"`
+	Lines      []GoodsReceiptLine `json:"lines"`
+	Total      float64            `json:"total"`
+
+	PurchaseOrderID *int `json:"purchase_order_id,omitempty"`
+}
+
+// GoodsReceiptLine создаёт партию; ExpiresAt — срок годности партии, если он есть.
+type GoodsReceiptLine struct {
+	InventoryID int        `json:"inventory_id"`
+	Name        string     `json:"name,omitempty"`
+	Quantity    Quantity   `json:"quantity"`
+	UnitCost    float64    `json:"unit_cost"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+
+	PurchaseOrderLineID *int `json:"purchase_order_line_id,omitempty"`
+}
+
+// InventoryTransaction — запись журнала движения остатков. Для прихода ChangeAmount
+// положителен, для списания отрицателен. Reference указывает документ-основание.
+type InventoryTransaction struct {
+	ID              int       `json:"id"`
+	InventoryID     int       `json:"inventory_id"`
+	ChangeAmount    Quantity  `json:"change_amount"`
+	UnitCost        *float64  `json:"unit_cost,omitempty"`
+	Reason          string    `json:"reason"`
+	ReferenceType   string    `json:"reference_type,omitempty"`
+	ReferenceID     *int      `json:"reference_id,omitempty"`
+	TransactionDate time.Time `json:"transaction_date"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// IdempotentResponse — сохранённый ответ на запрос с Idempotency-Key.
+// StatusCode == 0 означает, что первый запрос ещё выполняется.
+type IdempotentResponse struct {
+	RequestHash string
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
@@ -1,10 +1,60 @@
 package models
 
+import "time"
+
 type InventoryItem struct {
-	ID           int     `json:"id"`
-	Name         string  `json:"name"`
-	Quantity     int     `json:"quantity"`
-	Unit         string  `json:"unit"`
-	PricePerUnit float64 `json:"price_per_unit"`
-	LastUpdated  string  `json:"last_updated"`
+	ID           int        `json:"id"`
+	Name         string     `json:"name"`
+	Quantity     Quantity   `json:"quantity"`
+	Unit         string     `json:"unit"`
+	PricePerUnit float64    `json:"price_per_unit"`
+	LastUpdated  string     `json:"last_updated"`
+	Version      int        `json:"version"`
+	ArchivedAt   *time.Time `json:"archived_at,omitempty"`
+	ParLevel     *Quantity  `json:"par_level,omitempty"`      // минимальный запас после поставки
+	LeadTimeDays *int       `json:"lead_time_days,omitempty"` // если не задан, берётся срок поставщика
+
+	// Заполняются только для запроса с ?as_of: Quantity — остаток на этот момент.
+	AsOf        *time.Time   `json:"as_of,omitempty"`
+	LedgerCheck *LedgerCheck `json:"ledger_check,omitempty"`
+}
+
+// LedgerCheck — проверка, что журнал объясняет остаток. UnexplainedQuantity —
+// текущий остаток минус сумма всех движений; не ноль означает, что остаток
+// меняли в обход журнала, и восстановленное количество может быть неточным.
+type LedgerCheck struct {
+	Consistent          bool     `json:"consistent"`
+	UnexplainedQuantity Quantity `json:"unexplained_quantity"`
+	Issues              []string `json:"issues,omitempty"`
+}
+
+// InventoryUsage — где используется ингредиент: в рецепте блюда, в рецепте размера
+// (VariantSize) или в модификаторе (ModifierID).
+type InventoryUsage struct {
+	MenuItemID   int    `json:"menu_item_id,omitempty"`
+	MenuItemName string `json:"menu_item_name,omitempty"`
+	VariantSize  string `json:"variant_size,omitempty"`
+	ModifierID   int    `json:"modifier_id,omitempty"`
+	ModifierCode string `json:"modifier_code,omitempty"`
+}
+
+// InventoryReference — документ, который ссылается на ингредиент: строка заказа
+// поставщику (purchase_order), приёмки (goods_receipt) или каталога поставщика (supplier).
+type InventoryReference struct {
+	Type string `json:"type"`
+	ID   int    `json:"id"`
+}
+
+// InventoryDeletePlan описывает, что произойдёт при удалении ингредиента.
+// Action: "delete" — строка удаляется, "archive" — у ингредиента есть история движений,
+// поэтому он только скрывается. BlockedBy — документы, из-за которых строку нельзя
+// удалить совсем; архивировать ингредиент они не мешают.
+type InventoryDeletePlan struct {
+	InventoryID int                  `json:"inventory_id"`
+	Name        string               `json:"name"`
+	Action      string               `json:"action"`
+	HasHistory  bool                 `json:"has_history"`
+	UsedIn      []InventoryUsage     `json:"used_in"`
+	BlockedBy   []InventoryReference `json:"blocked_by,omitempty"`
+	DryRun      bool                 `json:"dry_run"`
 }
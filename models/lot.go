@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// InventoryLot — партия ингредиента. Quantity — остаток партии, сумма остатков
+// открытых партий не превышает inventory.quantity (разница — остаток без партии,
+// например после ручной правки количества).
+type InventoryLot struct {
+	ID                 int        `json:"id"`
+	InventoryID        int        `json:"inventory_id"`
+	Name               string     `json:"name,omitempty"`
+	Unit               string     `json:"unit,omitempty"`
+	Quantity           Quantity   `json:"quantity"`
+	InitialQuantity    Quantity   `json:"initial_quantity"`
+	UnitCost           *float64   `json:"unit_cost,omitempty"`
+	ReceivedAt         time.Time  `json:"received_at"`
+	ExpiresAt          *time.Time `json:"expires_at,omitempty"`
+	Expired            bool       `json:"expired"`
+	GoodsReceiptLineID *int       `json:"goods_receipt_line_id,omitempty"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// LoyaltyRule — сколько баллов даётся за единицу валюты, потраченную после скидок.
+// Правило без Category действует на весь заказ, с Category — добавляет бонусные
+// баллы за позиции этой категории.
+type LoyaltyRule struct {
+	ID            int     `json:"id"`
+	Name          string  `json:"name"`
+	Category      string  `json:"category,omitempty"`
+	PointsPerUnit float64 `json:"points_per_unit"`
+	Active        bool    `json:"active"`
+}
+
+// LoyaltyTier — уровень участника. Уровень определяется тратами за скользящее окно,
+// PointsMultiplier умножает баллы, начисленные по правилам.
+type LoyaltyTier struct {
+	ID               int     `json:"id"`
+	Name             string  `json:"name"`
+	MinSpend         float64 `json:"min_spend"`
+	PointsMultiplier float64 `json:"points_multiplier"`
+}
+
+// LoyaltyEntry — запись журнала баллов. Points положительные при начислении
+// и отрицательные при списании.
+type LoyaltyEntry struct {
+	ID        int       `json:"id"`
+	OrderID   *int      `json:"order_id,omitempty"`
+	Points    int       `json:"points"`
+	Reason    string    `json:"reason"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Balance   int       `json:"balance"` // остаток после этой записи
+}
+
+// CustomerLoyalty — состояние программы лояльности покупателя.
+type CustomerLoyalty struct {
+	CustomerID   int          `json:"customer_id"`
+	Balance      int          `json:"balance"`
+	PointsValue  float64      `json:"points_value"` // сколько стоят все баллы при оплате
+	RollingSpend float64      `json:"rolling_spend"`
+	WindowDays   int          `json:"window_days"`
+	Tier         *LoyaltyTier `json:"tier,omitempty"`
+	NextTier     *LoyaltyTier `json:"next_tier,omitempty"`
+	SpendToNext  float64      `json:"spend_to_next,omitempty"`
+}
+
+// CustomerLedger — журнал баллов покупателя, от старых записей к новым.
+type CustomerLedger struct {
+	CustomerID int            `json:"customer_id"`
+	Balance    int            `json:"balance"`
+	Entries    []LoyaltyEntry `json:"entries"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type MenuItem struct {
 	ID                   int                    `json:"id"`
 	Name                 string                 `json:"name"`
@@ -11,9 +13,34 @@ type MenuItem struct {
 	Size                 string                 `json:"size"`
 	Metadata             map[string]interface{} `json:"metadata"`
 	Ingredients          []IngredientInfo       `json:"ingredients"`
+	Variants             []MenuItemVariant      `json:"variants"`
+	ModifierIDs          []int                  `json:"modifier_ids"`
+	Version              int                    `json:"version"`
+	ArchivedAt           *time.Time             `json:"archived_at,omitempty"`
+	Available            bool                   `json:"available"`
+	MaxServings          *int                   `json:"max_servings"` // nil — остатки не ограничивают
 }
 
 type IngredientInfo struct {
-	IngredientID     int `json:"ingredient_id"`
-	QuantityRequired int `json:"quantity_required"`
+	IngredientID     int      `json:"ingredient_id"`
+	QuantityRequired Quantity `json:"quantity_required"`
+}
+
+// MenuItemVariant — размер блюда со своей ценой. Рецепт берётся из Ingredients,
+// если он задан, иначе базовый рецепт блюда умножается на RecipeMultiplier (по умолчанию 1).
+type MenuItemVariant struct {
+	ID               int              `json:"id"`
+	Size             string           `json:"size"`
+	Price            float64          `json:"price"`
+	RecipeMultiplier *Quantity        `json:"recipe_multiplier,omitempty"`
+	Ingredients      []IngredientInfo `json:"ingredients,omitempty"`
+	Available        bool             `json:"available"`
+	MaxServings      *int             `json:"max_servings"`
+}
+
+// RecipeLine — сколько ингредиента уходит на заказанную позицию.
+type RecipeLine struct {
+	IngredientID int      `json:"ingredient_id"`
+	Name         string   `json:"name"`
+	Quantity     Quantity `json:"quantity"`
 }
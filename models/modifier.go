@@ -0,0 +1,27 @@
+package models
+
+// Modifier — платная кастомизация позиции: доплата и изменение рецепта.
+type Modifier struct {
+	ID          int                  `json:"id"`
+	Code        string               `json:"code"`
+	Name        string               `json:"name"`
+	PriceDelta  float64              `json:"price_delta"`
+	Ingredients []ModifierIngredient `json:"ingredients"`
+}
+
+// ModifierIngredient добавляет ингредиент к рецепту или, если указан
+// ReplacesIngredientID, заменяет им другой ингредиент. Для замены Quantity
+// можно не указывать — тогда берётся количество заменяемого ингредиента.
+type ModifierIngredient struct {
+	IngredientID         int       `json:"ingredient_id"`
+	Quantity             *Quantity `json:"quantity,omitempty"`
+	ReplacesIngredientID *int      `json:"replaces_ingredient_id,omitempty"`
+}
+
+// OrderItemModifier — модификатор, выбранный в позиции заказа.
+// PriceDelta фиксируется на момент заказа.
+type OrderItemModifier struct {
+	ModifierID int     `json:"modifier_id"`
+	Quantity   int     `json:"quantity"`
+	PriceDelta float64 `json:"price_delta"`
+}
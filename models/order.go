@@ -7,6 +7,16 @@ type Order struct {
 	CustomerID          int                    `json:"customer_id"`
 	Status              string                 `json:"status"`
 	SpecialInstructions map[string]interface{} `json:"special_instructions"`
+	PromoCode           string                 `json:"promo_code,omitempty"`
+	Subtotal            float64                `json:"subtotal"`
+	DiscountTotal       float64                `json:"discount_total"`
+	Discounts           []OrderDiscount        `json:"discounts,omitempty"`
+	TaxTotal            float64                `json:"tax_total"`
+	TaxLines            []OrderTaxLine         `json:"tax_lines,omitempty"`
+	TipAmount           float64                `json:"tip_amount"`
+	TipPercent          *float64               `json:"tip_percent,omitempty"`
 	TotalAmount         float64                `json:"total_amount"`
+	RedeemPoints        int                    `json:"redeem_points,omitempty"`   // сколько баллов покупатель хочет списать
+	PointsRedeemed      int                    `json:"points_redeemed,omitempty"` // сколько списано фактически
 	OrderDate           time.Time              `json:"order_date"`
 }
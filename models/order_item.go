@@ -4,7 +4,9 @@ type OrderItem struct {
 	ID               int                    `json:"id"`
 	OrderID          int                    `json:"order_id"`
 	MenuItemID       int                    `json:"menu_item_id"`
+	VariantID        *int                   `json:"variant_id,omitempty"`
 	Quantity         int                    `json:"quantity"`
 	PriceAtOrderTime float64                `json:"price_at_order_time"`
+	Modifiers        []OrderItemModifier    `json:"modifiers"`
 	Customization    map[string]interface{} `json:"customization"`
 }
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+type Payment struct {
+	ID      int       `json:"id"`
+	OrderID int       `json:"order_id"`
+	Method  string    `json:"method"`
+	Amount  float64   `json:"amount"`
+	PaidAt  time.Time `json:"paid_at"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Promotion — скидка. Без Code применяется автоматически, с Code — только по промокоду.
+// Type: percentage (Value — процент), fixed (Value — сумма), bogo (каждая вторая
+// единица бесплатно, дешевле — бесплатно). Category ограничивает скидку позициями
+// этой категории. HappyHourStart/HappyHourEnd ("15:00") задают ежедневное окно.
+type Promotion struct {
+	ID               int        `json:"id"`
+	Name             string     `json:"name"`
+	Code             string     `json:"code,omitempty"`
+	Type             string     `json:"type"`
+	Value            float64    `json:"value"`
+	Category         string     `json:"category,omitempty"`
+	StartsAt         *time.Time `json:"starts_at,omitempty"`
+	EndsAt           *time.Time `json:"ends_at,omitempty"`
+	HappyHourStart   string     `json:"happy_hour_start,omitempty"`
+	HappyHourEnd     string     `json:"happy_hour_end,omitempty"`
+	UsageLimit       *int       `json:"usage_limit,omitempty"`
+	PerCustomerLimit *int       `json:"per_customer_limit,omitempty"` // такие акции не применяются к гостевым заказам
+	Active           bool       `json:"active"`
+	TimesUsed        int        `json:"times_used"`
+}
+
+// OrderDiscount — скидка, применённая к заказу. Оплата баллами тоже скидка:
+// у неё нет PromotionID, зато есть Points.
+type OrderDiscount struct {
+	PromotionID int     `json:"promotion_id,omitempty"`
+	Points      int     `json:"points,omitempty"`
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"`
+}
+
+// PricingLine — позиция заказа в том виде, в котором её видит расчёт цены.
+type PricingLine struct {
+	OrderItemID int
+	Categories  []string
+	UnitPrice   float64
+	Quantity    int
+}
@@ -0,0 +1,276 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"math/bits"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// MaxQuantityPrecision совпадает с масштабом колонок NUMERIC(18,6) в init.sql.
+const MaxQuantityPrecision = 6
+
+// MaxQuantityIntDigits — сколько цифр до запятой вмещает NUMERIC(18,6).
+const MaxQuantityIntDigits = 18 - MaxQuantityPrecision
+
+// QuantityPrecision — число знаков после запятой для количеств ингредиентов.
+// Задаётся переменной окружения QUANTITY_PRECISION (0..6), по умолчанию 3.
+var QuantityPrecision = 3
+
+var quantityScale int64 = 1000
+
+func init() {
+	if v := os.Getenv("QUANTITY_PRECISION"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil || p < 0 || p > MaxQuantityPrecision {
+			panic(fmt.Sprintf("QUANTITY_PRECISION должен быть целым числом от 0 до %d, получено %q", MaxQuantityPrecision, v))
+		}
+		SetQuantityPrecision(p)
+	}
+}
+
+// SetQuantityPrecision меняет точность. Вызывать только при старте.
+func SetQuantityPrecision(p int) {
+	QuantityPrecision = p
+	quantityScale = 1
+	for i := 0; i < p; i++ {
+		quantityScale *= 10
+	}
+}
+
+// Quantity — количество с фиксированной точкой: целое число минимальных
+// единиц (10^-QuantityPrecision). Арифметика целочисленная, поэтому
+// 7.5 г + 0.1 г всегда дают ровно 7.6 г, без дрейфа float.
+type Quantity int64
+
+// ParseQuantity разбирает десятичную строку вида "7.5" или "-0.25".
+// Если знаков после запятой больше, чем позволяет точность, возвращается ошибка.
+func ParseQuantity(s string) (Quantity, error) {
+	return parseQuantity(s, true)
+}
+
+// NewQuantity возвращает целое количество n.
+func NewQuantity(n int64) Quantity {
+	return Quantity(n * quantityScale)
+}
+
+// QuantityFromFloat64 округляет float до текущей точности (для прогнозов и
+// других расчётов, где точная арифметика не нужна).
+func QuantityFromFloat64(f float64) Quantity {
+	return Quantity(math.Round(f * float64(quantityScale)))
+}
+
+func parseQuantity(s string, strict bool) (Quantity, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("пустое количество")
+	}
+
+	neg := false
+	if s[0] == '-' || s[0] == '+' {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+
+	intPart, fracPart := s, ""
+	if dot := strings.IndexByte(s, '.'); dot >= 0 {
+		intPart, fracPart = s[:dot], s[dot+1:]
+	}
+	if intPart == "" && fracPart == "" {
+		return 0, fmt.Errorf("некорректное количество %q", s)
+	}
+	for _, part := range []string{intPart, fracPart} {
+		for _, c := range part {
+			if c < '0' || c > '9' {
+				return 0, fmt.Errorf("некорректное количество %q", s)
+			}
+		}
+	}
+
+	// Округление половины от нуля применяется только при нестрогом разборе
+	// (значения из БД, если точность уменьшили после записи).
+	roundUp := false
+	if len(fracPart) > QuantityPrecision {
+		extra := strings.TrimRight(fracPart[QuantityPrecision:], "0")
+		if extra != "" {
+			if strict {
+				return 0, fmt.Errorf("количество %q: допускается не более %d знаков после запятой", s, QuantityPrecision)
+			}
+			roundUp = extra[0] >= '5'
+		}
+		fracPart = fracPart[:QuantityPrecision]
+	}
+	fracPart += strings.Repeat("0", QuantityPrecision-len(fracPart))
+
+	if len(strings.TrimLeft(intPart, "0")) > MaxQuantityIntDigits {
+		return 0, fmt.Errorf("количество %q слишком велико: допускается не более %d знаков до запятой", s, MaxQuantityIntDigits)
+	}
+	digits := strings.TrimLeft(intPart+fracPart, "0")
+	if digits == "" {
+		digits = "0"
+	}
+
+	v, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("некорректное количество %q: %v", s, err)
+	}
+	if roundUp {
+		v++
+	}
+	if neg {
+		v = -v
+	}
+	return Quantity(v), nil
+}
+
+// String форматирует количество без лишних нулей: 7.5, 100, 0.25.
+func (q Quantity) String() string {
+	v := int64(q)
+	sign := ""
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+
+	intPart := v / quantityScale
+	frac := v % quantityScale
+	if frac == 0 || QuantityPrecision == 0 {
+		return sign + strconv.FormatInt(intPart, 10)
+	}
+
+	fracStr := strconv.FormatInt(frac, 10)
+	fracStr = strings.Repeat("0", QuantityPrecision-len(fracStr)) + fracStr
+	return sign + strconv.FormatInt(intPart, 10) + "." + strings.TrimRight(fracStr, "0")
+}
+
+// Float64 нужен только для денежных расчётов (price_per_unit хранится как float64).
+func (q Quantity) Float64() float64 {
+	return float64(q) / float64(quantityScale)
+}
+
+// maxQuantity — наибольшее по модулю количество, которое помещается в колонку.
+func maxQuantity() uint64 {
+	limit := uint64(quantityScale)
+	for i := 0; i < MaxQuantityIntDigits; i++ {
+		limit *= 10
+	}
+	return limit - 1
+}
+
+func abs64(v int64) uint64 {
+	if v < 0 {
+		return uint64(-v)
+	}
+	return uint64(v)
+}
+
+func errQuantityOverflow(q Quantity, op string, arg interface{}) error {
+	return fmt.Errorf("количество %s %s %v слишком велико: допускается не более %d знаков до запятой", q, op, arg, MaxQuantityIntDigits)
+}
+
+// Mul умножает количество на целое число порций. Если результат не помещается
+// в колонку, возвращается ошибка.
+func (q Quantity) Mul(n int) (Quantity, error) {
+	hi, lo := bits.Mul64(abs64(int64(q)), abs64(int64(n)))
+	if hi != 0 || lo > maxQuantity() {
+		return 0, errQuantityOverflow(q, "×", n)
+	}
+	return q * Quantity(n), nil
+}
+
+// Div делит количество на целое n (например, расход за период на число дней),
+// округляя половиной от нуля.
+func (q Quantity) Div(n int) Quantity {
+	if n == 0 {
+		return 0
+	}
+	v, d := int64(q), int64(n)
+	if (v < 0) != (d < 0) {
+		return Quantity((v - d/2) / d)
+	}
+	return Quantity((v + d/2) / d)
+}
+
+// MulQuantity умножает два количества (например, рецепт на коэффициент размера),
+// округляя результат половиной от нуля до текущей точности. Произведение
+// считается в 128 битах, поэтому промежуточное значение не переполняется.
+func (q Quantity) MulQuantity(factor Quantity) (Quantity, error) {
+	scale := uint64(quantityScale)
+	hi, lo := bits.Mul64(abs64(int64(q)), abs64(int64(factor)))
+	if hi >= scale {
+		return 0, errQuantityOverflow(q, "×", factor)
+	}
+	v, rem := bits.Div64(hi, lo, scale)
+	if rem >= scale-rem {
+		v++
+	}
+	if v > maxQuantity() {
+		return 0, errQuantityOverflow(q, "×", factor)
+	}
+	if (q < 0) != (factor < 0) {
+		return -Quantity(v), nil
+	}
+	return Quantity(v), nil
+}
+
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return []byte(q.String()), nil
+}
+
+// UnmarshalJSON принимает как число (7.5), так и строку ("7.5").
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		*q = 0
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		unquoted, err := strconv.Unquote(s)
+		if err != nil {
+			return fmt.Errorf("некорректное количество %s", s)
+		}
+		s = unquoted
+	}
+	v, err := ParseQuantity(s)
+	if err != nil {
+		return err
+	}
+	*q = v
+	return nil
+}
+
+// Scan читает значение NUMERIC из БД.
+func (q *Quantity) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*q = 0
+		return nil
+	case []byte:
+		parsed, err := parseQuantity(string(v), false)
+		if err != nil {
+			return err
+		}
+		*q = parsed
+		return nil
+	case string:
+		parsed, err := parseQuantity(v, false)
+		if err != nil {
+			return err
+		}
+		*q = parsed
+		return nil
+	case int64:
+		*q = NewQuantity(v)
+		return nil
+	default:
+		return fmt.Errorf("не удалось прочитать количество из %T", src)
+	}
+}
+
+// Value передаёт количество в БД строкой, чтобы NUMERIC получил точное значение.
+func (q Quantity) Value() (driver.Value, error) {
+	return q.String(), nil
+}
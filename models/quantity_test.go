@@ -0,0 +1,237 @@
+package models
+
+import "testing"
+
+func withPrecision(t *testing.T, p int) {
+	t.Helper()
+	old := QuantityPrecision
+	SetQuantityPrecision(p)
+	t.Cleanup(func() { SetQuantityPrecision(old) })
+}
+
+func TestParseQuantity(t *testing.T) {
+	withPrecision(t, 3)
+
+	tests := []struct {
+		in      string
+		want    Quantity
+		wantErr bool
+	}{
+		{in: "7.5", want: 7500},
+		{in: "-0.25", want: -250},
+		{in: "+1", want: 1000},
+		{in: " 100 ", want: 100000},
+		{in: ".5", want: 500},
+		{in: "5.", want: 5000},
+		{in: "0.1000", want: 100},
+		{in: "007.010", want: 7010},
+		{in: "999999999999.999", want: 999999999999999},
+		{in: "000999999999999", want: 999999999999000},
+		{in: "1000000000000", wantErr: true},
+		{in: "0.0001", wantErr: true},
+		{in: "", wantErr: true},
+		{in: ".", wantErr: true},
+		{in: "-", wantErr: true},
+		{in: "1e3", wantErr: true},
+		{in: "1.2.3", wantErr: true},
+		{in: "--1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseQuantity(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseQuantity(%q) = %d, ожидалась ошибка", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseQuantity(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseQuantity(%q) = %d, ожидалось %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestScanRoundsToPrecision(t *testing.T) {
+	withPrecision(t, 2)
+
+	tests := []struct {
+		in   string
+		want Quantity
+	}{
+		{in: "7.125000", want: 713},
+		{in: "7.124999", want: 712},
+		{in: "-7.125000", want: -713},
+		{in: "999999999999.990000", want: 99999999999999},
+	}
+
+	for _, tt := range tests {
+		var got Quantity
+		if err := got.Scan([]byte(tt.in)); err != nil {
+			t.Errorf("Scan(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Scan(%q) = %d, ожидалось %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestQuantityString(t *testing.T) {
+	tests := []struct {
+		precision int
+		in        Quantity
+		want      string
+	}{
+		{precision: 3, in: 7500, want: "7.5"},
+		{precision: 3, in: 100000, want: "100"},
+		{precision: 3, in: 250, want: "0.25"},
+		{precision: 3, in: -250, want: "-0.25"},
+		{precision: 3, in: 1, want: "0.001"},
+		{precision: 3, in: 0, want: "0"},
+		{precision: 0, in: 42, want: "42"},
+		{precision: 6, in: 1000001, want: "1.000001"},
+	}
+
+	for _, tt := range tests {
+		withPrecision(t, tt.precision)
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Quantity(%d).String() при точности %d = %q, ожидалось %q", tt.in, tt.precision, got, tt.want)
+		}
+	}
+}
+
+func TestQuantityMul(t *testing.T) {
+	withPrecision(t, 3)
+
+	tests := []struct {
+		q       Quantity
+		n       int
+		want    Quantity
+		wantErr bool
+	}{
+		{q: 7500, n: 2, want: 15000},
+		{q: 7500, n: -2, want: -15000},
+		{q: 7500, n: 0, want: 0},
+		{q: 999999999999999, n: 1, want: 999999999999999},
+		{q: 500000000000000, n: 2, wantErr: true},
+		{q: 1 << 40, n: 1 << 40, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.q.Mul(tt.n)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%d.Mul(%d) = %d, ожидалась ошибка", tt.q, tt.n, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d.Mul(%d): %v", tt.q, tt.n, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%d.Mul(%d) = %d, ожидалось %d", tt.q, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestQuantityMulQuantity(t *testing.T) {
+	withPrecision(t, 3)
+
+	tests := []struct {
+		q, factor Quantity
+		want      Quantity
+		wantErr   bool
+	}{
+		{q: 200000, factor: 1500, want: 300000}, // 200 × 1.5 = 300
+		{q: 7, factor: 500, want: 4},            // 0.007 × 0.5 = 0.0035 → 0.004
+		{q: -7, factor: 500, want: -4},          // округление от нуля
+		{q: 7, factor: -500, want: -4},          // знак множителя
+		{q: 3, factor: 100, want: 0},            // 0.003 × 0.1 = 0.0003 → 0
+		{q: 999999999999999, factor: 1000, want: 999999999999999},
+		// Промежуточное произведение больше int64, но результат помещается
+		{q: 900000000000000, factor: 1000, want: 900000000000000},
+		{q: 999999999999999, factor: 1001, wantErr: true},
+		{q: 999999999999999, factor: 999999999999999, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.q.MulQuantity(tt.factor)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%d.MulQuantity(%d) = %d, ожидалась ошибка", tt.q, tt.factor, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d.MulQuantity(%d): %v", tt.q, tt.factor, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%d.MulQuantity(%d) = %d, ожидалось %d", tt.q, tt.factor, got, tt.want)
+		}
+	}
+}
+
+func TestQuantityDiv(t *testing.T) {
+	tests := []struct {
+		q    Quantity
+		n    int
+		want Quantity
+	}{
+		{q: 10000, n: 4, want: 2500},
+		{q: 10, n: 4, want: 3}, // 2.5 → 3
+		{q: 9, n: 4, want: 2},  // 2.25 → 2
+		{q: -10, n: 4, want: -3},
+		{q: 10, n: -4, want: -3},
+		{q: -10, n: -4, want: 3},
+		{q: 10, n: 0, want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := tt.q.Div(tt.n); got != tt.want {
+			t.Errorf("%d.Div(%d) = %d, ожидалось %d", tt.q, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestQuantityUnmarshalJSON(t *testing.T) {
+	withPrecision(t, 3)
+
+	tests := []struct {
+		in      string
+		want    Quantity
+		wantErr bool
+	}{
+		{in: `7.5`, want: 7500},
+		{in: `"7.5"`, want: 7500},
+		{in: `null`, want: 0},
+		{in: `"7.5`, wantErr: true},
+		{in: `7.5"`, wantErr: true},
+		{in: `""`, wantErr: true},
+		{in: `"null"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		// Вызываем напрямую: json.Unmarshal отбросил бы часть входов ещё до разбора
+		got := Quantity(-1)
+		err := got.UnmarshalJSON([]byte(tt.in))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Unmarshal(%s) = %d, ожидалась ошибка", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, ожидалось %d", tt.in, got, tt.want)
+		}
+	}
+}
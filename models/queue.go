@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// QueueTicket — заказ в очереди бариста.
+type QueueTicket struct {
+	OrderID             int                    `json:"order_id"`
+	CustomerID          int                    `json:"customer_id"`
+	Status              string                 `json:"status"`
+	OrderDate           time.Time              `json:"order_date"`
+	SpecialInstructions map[string]interface{} `json:"special_instructions"`
+	AssignedStaffID     *int                   `json:"assigned_staff_id,omitempty"`
+	AssignedStaffName   string                 `json:"assigned_staff_name,omitempty"`
+	ClaimedAt           *time.Time             `json:"claimed_at,omitempty"`
+	Items               []OrderItemDetail      `json:"items"`
+}
+
+type Staff struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
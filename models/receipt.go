@@ -0,0 +1,22 @@
+package models
+
+// Receipt — всё, что печатается на чеке заказа.
+type Receipt struct {
+	Order     Order             `json:"order"`
+	Items     []OrderItemDetail `json:"items"`
+	Payments  []Payment         `json:"payments"`
+	PaidTotal float64           `json:"paid_total"`
+}
+
+// OrderItemDetail — позиция заказа в читаемом виде: название, размер,
+// названия модификаторов и пожелания.
+type OrderItemDetail struct {
+	OrderItemID   int                    `json:"order_item_id"`
+	Name          string                 `json:"name"`
+	Size          string                 `json:"size,omitempty"`
+	Quantity      int                    `json:"quantity"`
+	UnitPrice     float64                `json:"unit_price"`
+	LineTotal     float64                `json:"line_total"`
+	Modifiers     []string               `json:"modifiers,omitempty"`
+	Customization map[string]interface{} `json:"customization,omitempty"`
+}
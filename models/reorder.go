@@ -0,0 +1,38 @@
+package models
+
+// ReorderSuggestion — предложение дозаказать ингредиент.
+// AvgDailyUsage считается по списаниям заказов за последние дни,
+// ProjectedAtArrival = Quantity + OnOrder - AvgDailyUsage × LeadTimeDays.
+// Если прогноз ниже ParLevel, предлагается довести запас до ParLevel
+// с учётом расхода за время поставки, округлив вверх до целых упаковок.
+type ReorderSuggestion struct {
+	InventoryID        int      `json:"inventory_id"`
+	Name               string   `json:"name"`
+	Unit               string   `json:"unit"`
+	Quantity           Quantity `json:"quantity"`
+	OnOrder            Quantity `json:"on_order"`
+	ParLevel           Quantity `json:"par_level"`
+	LeadTimeDays       int      `json:"lead_time_days"`
+	AvgDailyUsage      Quantity `json:"avg_daily_usage"`
+	ProjectedAtArrival Quantity `json:"projected_at_arrival"`
+	SuggestedQuantity  Quantity `json:"suggested_quantity"`
+	SupplierID         *int     `json:"supplier_id,omitempty"`
+	SupplierName       string   `json:"supplier_name,omitempty"`
+	SupplierSKU        string   `json:"supplier_sku,omitempty"`
+	PackSize           Quantity `json:"pack_size,omitempty"`
+	PackPrice          float64  `json:"pack_price,omitempty"`
+	Packs              int      `json:"packs,omitempty"`
+	EstimatedCost      float64  `json:"estimated_cost"`
+}
+
+// OrderSheetRequest — принятые предложения. Для каждой строки можно
+// переопределить число упаковок; пустой список означает «принять все».
+type OrderSheetRequest struct {
+	Days  int              `json:"days,omitempty"`
+	Lines []OrderSheetLine `json:"lines"`
+}
+
+type OrderSheetLine struct {
+	InventoryID int `json:"inventory_id"`
+	Packs       int `json:"packs,omitempty"`
+}
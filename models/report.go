@@ -0,0 +1,21 @@
+package models
+
+// DiscountReportRow — сколько стоила акция за период.
+type DiscountReportRow struct {
+	PromotionID   int     `json:"promotion_id"`
+	Name          string  `json:"name"`
+	Code          string  `json:"code,omitempty"`
+	Orders        int     `json:"orders"`
+	DiscountTotal float64 `json:"discount_total"`
+}
+
+// StatusDurationRow — сколько заказы находились в статусе. Slice — значение
+// разреза (час, день недели или категория), пустое без разреза.
+type StatusDurationRow struct {
+	Status        string  `json:"status"`
+	Slice         string  `json:"slice,omitempty"`
+	Samples       int     `json:"samples"`
+	AvgSeconds    float64 `json:"avg_seconds"`
+	MedianSeconds float64 `json:"median_seconds"`
+	P90Seconds    float64 `json:"p90_seconds"`
+}
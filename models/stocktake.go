@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Статусы инвентаризации: open → approved или open → canceled.
+const (
+	StocktakeOpen     = "open"
+	StocktakeApproved = "approved"
+	StocktakeCanceled = "canceled"
+)
+
+// Stocktake — инвентаризация. При старте фиксируется учётный остаток каждой
+// позиции, при пересчёте — учётный остаток на этот момент; расхождение считается
+// от второго, а при утверждении прибавляется к текущему остатку, чтобы продажи
+// и поступления во время инвентаризации не потерялись и не учлись дважды.
+type Stocktake struct {
+	ID           int             `json:"id"`
+	Status       string          `json:"status"`
+	Note         string          `json:"note,omitempty"`
+	StartedAt    time.Time       `json:"started_at"`
+	ApprovedAt   *time.Time      `json:"approved_at,omitempty"`
+	CanceledAt   *time.Time      `json:"canceled_at,omitempty"`
+	Lines        []StocktakeLine `json:"lines"`
+	Counted      int             `json:"counted"`
+	VarianceCost float64         `json:"variance_cost"`
+
+	// InventoryIDs ограничивает пересчёт при создании; пусто — весь активный склад.
+	InventoryIDs []int `json:"inventory_ids,omitempty"`
+}
+
+// StocktakeLine — позиция пересчёта. SystemQuantity — учётный остаток на старте,
+// ExpectedQuantity — в момент пересчёта. Variance = CountedQuantity - ExpectedQuantity,
+// заполняется только для посчитанных позиций.
+type StocktakeLine struct {
+	InventoryID      int        `json:"inventory_id"`
+	Name             string     `json:"name,omitempty"`
+	Unit             string     `json:"unit,omitempty"`
+	SystemQuantity   Quantity   `json:"system_quantity"`
+	CountedQuantity  *Quantity  `json:"counted_quantity"`
+	CountedAt        *time.Time `json:"counted_at,omitempty"`
+	ExpectedQuantity *Quantity  `json:"expected_quantity,omitempty"`
+	Variance         *Quantity  `json:"variance,omitempty"`
+	UnitCost         *float64   `json:"unit_cost,omitempty"`
+	VarianceCost     *float64   `json:"variance_cost,omitempty"`
+}
+
+type StocktakeCount struct {
+	InventoryID     int      `json:"inventory_id"`
+	CountedQuantity Quantity `json:"counted_quantity"`
+}
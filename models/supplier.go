@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+type Supplier struct {
+	ID           int            `json:"id"`
+	Name         string         `json:"name"`
+	ContactName  string         `json:"contact_name,omitempty"`
+	Phone        string         `json:"phone,omitempty"`
+	Email        string         `json:"email,omitempty"`
+	LeadTimeDays int            `json:"lead_time_days"`
+	Active       bool           `json:"active"`
+	Items        []SupplierItem `json:"items"`
+}
+
+// SupplierItem — позиция каталога поставщика. PackSize — в единицах ингредиента
+// (например, 1000 grams), PackPrice — цена упаковки.
+type SupplierItem struct {
+	InventoryID int      `json:"inventory_id"`
+	Name        string   `json:"name,omitempty"`
+	SupplierSKU string   `json:"supplier_sku,omitempty"`
+	PackSize    Quantity `json:"pack_size"`
+	PackPrice   float64  `json:"pack_price"`
+}
+
+// Статусы заказа поставщику:
+// draft → sent → partially_received → received → closed.
+// Черновик можно закрыть (отмена), частично принятый — закрыть с недопоставкой.
+const (
+	PurchaseOrderDraft             = "draft"
+	PurchaseOrderSent              = "sent"
+	PurchaseOrderPartiallyReceived = "partially_received"
+	PurchaseOrderReceived          = "received"
+	PurchaseOrderClosed            = "closed"
+)
+
+type PurchaseOrder struct {
+	ID           int                 `json:"id"`
+	SupplierID   int                 `json:"supplier_id"`
+	SupplierName string              `json:"supplier_name,omitempty"`
+	Status       string              `json:"status"`
+	Note         string              `json:"note,omitempty"`
+	CreatedAt    time.Time           `json:"created_at"`
+	SentAt       *time.Time          `json:"sent_at,omitempty"`
+	ExpectedAt   *time.Time          `json:"expected_at,omitempty"`
+	ClosedAt     *time.Time          `json:"closed_at,omitempty"`
+	Lines        []PurchaseOrderLine `json:"lines"`
+	Total        float64             `json:"total"`
+}
+
+// PurchaseOrderLine заказывается упаковками из каталога; размер и цена упаковки
+// фиксируются на момент создания заказа. Quantity = Packs × PackSize.
+type PurchaseOrderLine struct {
+	ID               int      `json:"id"`
+	InventoryID      int      `json:"inventory_id"`
+	Name             string   `json:"name,omitempty"`
+	Packs            int      `json:"packs"`
+	PackSize         Quantity `json:"pack_size"`
+	PackPrice        float64  `json:"pack_price"`
+	Quantity         Quantity `json:"quantity"`
+	ReceivedQuantity Quantity `json:"received_quantity"`
+}
+
+// PurchaseOrderReceiving — приёмка по заказу. UnitCost — фактическая цена за единицу;
+// если не указана, берётся цена из заказа (PackPrice / PackSize).
+type PurchaseOrderReceiving struct {
+	Reference string                       `json:"reference,omitempty"`
+	Note      string                       `json:"note,omitempty"`
+	Lines     []PurchaseOrderReceivingLine `json:"lines"`
+}
+
+type PurchaseOrderReceivingLine struct {
+	LineID    int        `json:"line_id"`
+	Quantity  Quantity   `json:"quantity"`
+	UnitCost  *float64   `json:"unit_cost,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
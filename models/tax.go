@@ -0,0 +1,22 @@
+package models
+
+// TaxRate — ставка налога в процентах. Без Category действует для позиций,
+// к которым не подошла ни одна ставка категории. Inclusive — налог уже входит в цену.
+type TaxRate struct {
+	ID        int     `json:"id"`
+	Name      string  `json:"name"`
+	Rate      float64 `json:"rate"`
+	Category  string  `json:"category,omitempty"`
+	Inclusive bool    `json:"inclusive"`
+	Active    bool    `json:"active"`
+}
+
+// OrderTaxLine — налог по одной ставке в заказе.
+type OrderTaxLine struct {
+	TaxRateID     int     `json:"tax_rate_id"`
+	Name          string  `json:"name"`
+	Rate          float64 `json:"rate"`
+	Inclusive     bool    `json:"inclusive"`
+	TaxableAmount float64 `json:"taxable_amount"`
+	Amount        float64 `json:"amount"`
+}
@@ -0,0 +1,25 @@
+package models
+
+// Методы оценки запасов
+const (
+	ValuationAverage = "average"
+	ValuationFIFO    = "fifo"
+)
+
+// InventoryValuation — стоимость остатков на дату. AsOf пусто для текущих остатков.
+type InventoryValuation struct {
+	Method string                  `json:"method"`
+	AsOf   string                  `json:"as_of,omitempty"`
+	Items  []InventoryValuationRow `json:"items"`
+	Total  float64                 `json:"total"`
+}
+
+// InventoryValuationRow — остаток позиции и его стоимость; UnitCost = Value / Quantity.
+type InventoryValuationRow struct {
+	InventoryID int      `json:"inventory_id"`
+	Name        string   `json:"name"`
+	Unit        string   `json:"unit"`
+	Quantity    Quantity `json:"quantity"`
+	UnitCost    float64  `json:"unit_cost"`
+	Value       float64  `json:"value"`
+}
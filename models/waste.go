@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Причины списания в waste_entries.reason
+const (
+	WasteSpoiled = "spoiled"
+	WasteSpilled = "spilled"
+	WasteRemake  = "remake"
+	WasteExpired = "expired"
+)
+
+// WasteEntry — списание ингредиента без продажи. UnitCost — цена за единицу
+// на момент списания, Cost = Quantity × UnitCost.
+type WasteEntry struct {
+	ID          int       `json:"id"`
+	InventoryID int       `json:"inventory_id"`
+	Name        string    `json:"name,omitempty"`
+	Quantity    Quantity  `json:"quantity"`
+	Reason      string    `json:"reason"`
+	Note        string    `json:"note,omitempty"`
+	UnitCost    *float64  `json:"unit_cost,omitempty"`
+	Cost        float64   `json:"cost"`
+	LotID       *int      `json:"lot_id,omitempty"`
+	RecordedAt  time.Time `json:"recorded_at"`
+}
+
+// WasteReportRow — потери по ингредиенту и причине за период. Period — начало
+// периода (день, неделя или месяц), пустое без разбивки.
+type WasteReportRow struct {
+	Period      string   `json:"period,omitempty"`
+	InventoryID int      `json:"inventory_id"`
+	Name        string   `json:"name"`
+	Unit        string   `json:"unit"`
+	Reason      string   `json:"reason"`
+	Entries     int      `json:"entries"`
+	Quantity    Quantity `json:"quantity"`
+	Cost        float64  `json:"cost"`
+}
@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"frappuccino/models"
+)
+
+// Сколько порций можно приготовить из текущих остатков: минимум по ингредиентам
+// рецепта от floor(остаток / расход). Рецепт размера собирается так же, как в recipeQuery:
+// собственный рецепт варианта или базовый × recipe_multiplier. Строки с variant_id NULL —
+// базовый рецепт блюда без размера. Просроченные партии, которые фоновая задача
+// ещё не списала, не считаются: заказ всё равно спишет их перед расходом.
+const availabilityQuery = `
+	WITH recipes AS (
+		SELECT mii.menu_item_id, NULL::INTEGER AS variant_id, mii.ingredient_id, mii.quantity_required AS qty
+		FROM menu_item_ingredients mii
+		UNION ALL
+		SELECT v.menu_item_id, v.id, vi.ingredient_id, vi.quantity_required
+		FROM menu_item_variants v
+		JOIN menu_item_variant_ingredients vi ON vi.variant_id = v.id
+		UNION ALL
+		SELECT v.menu_item_id, v.id, mii.ingredient_id, mii.quantity_required * COALESCE(v.recipe_multiplier, 1)
+		FROM menu_item_variants v
+		JOIN menu_item_ingredients mii ON mii.menu_item_id = v.menu_item_id
+		WHERE NOT EXISTS (SELECT 1 FROM menu_item_variant_ingredients vi WHERE vi.variant_id = v.id)
+	)
+	SELECT r.menu_item_id, r.variant_id,
+		MIN(GREATEST(FLOOR((i.quantity - COALESCE(x.qty, 0)) / NULLIF(r.qty, 0)), 0))::INTEGER
+	FROM (
+		SELECT menu_item_id, variant_id, ingredient_id, SUM(qty) AS qty
+		FROM recipes
+		WHERE $1 = 0 OR menu_item_id = $1
+		GROUP BY menu_item_id, variant_id, ingredient_id
+	) r
+	JOIN inventory i ON i.id = r.ingredient_id
+	LEFT JOIN (
+		SELECT inventory_id, SUM(quantity) AS qty
+		FROM inventory_lots
+		WHERE quantity > 0 AND expires_at <= NOW()
+		GROUP BY inventory_id
+	) x ON x.inventory_id = i.id
+	GROUP BY r.menu_item_id, r.variant_id`
+
+// servings — результат availabilityQuery для одного блюда: base — без размера,
+// variants — по ID размера. Отсутствие ключа значит, что рецепт остатками не ограничен.
+type servings struct {
+	base     *int
+	variants map[int]*int
+}
+
+// getMenuAvailability считает доступные порции всех блюд (menuItemID == 0) или одного.
+func getMenuAvailability(q queryer, menuItemID int) (map[int]*servings, error) {
+	rows, err := q.Query(availabilityQuery, menuItemID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при расчёте доступности меню: %v", err)
+	}
+	defer rows.Close()
+
+	result := make(map[int]*servings)
+	for rows.Next() {
+		var itemID int
+		var variantID, maxServings sql.NullInt64
+		if err := rows.Scan(&itemID, &variantID, &maxServings); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании доступности: %v", err)
+		}
+
+		s := result[itemID]
+		if s == nil {
+			s = &servings{variants: make(map[int]*int)}
+			result[itemID] = s
+		}
+
+		var max *int
+		if maxServings.Valid {
+			v := int(maxServings.Int64)
+			max = &v
+		}
+		if variantID.Valid {
+			s.variants[int(variantID.Int64)] = max
+		} else {
+			s.base = max
+		}
+	}
+
+	return result, rows.Err()
+}
+
+// applyAvailability заполняет Available и MaxServings блюда и его размеров.
+// Блюдо с размерами доступно, если можно приготовить хотя бы один размер;
+// MaxServings у него — наибольшее число порций среди размеров.
+// Архивное блюдо недоступно всегда.
+func applyAvailability(item *models.MenuItem, s *servings) {
+	if s == nil {
+		s = &servings{}
+	}
+
+	if len(item.Variants) == 0 {
+		item.MaxServings = s.base
+		item.Available = item.MaxServings == nil || *item.MaxServings > 0
+	} else {
+		item.Available = false
+		item.MaxServings = nil
+		unlimited := false
+		for i := range item.Variants {
+			v := &item.Variants[i]
+			v.MaxServings = s.variants[v.ID]
+			v.Available = v.MaxServings == nil || *v.MaxServings > 0
+			if v.MaxServings == nil {
+				unlimited = true
+			} else if !unlimited && (item.MaxServings == nil || *v.MaxServings > *item.MaxServings) {
+				item.MaxServings = v.MaxServings
+			}
+			item.Available = item.Available || v.Available
+		}
+		if unlimited {
+			item.MaxServings = nil
+		}
+	}
+
+	if item.ArchivedAt != nil {
+		item.Available = false
+		for i := range item.Variants {
+			item.Variants[i].Available = false
+		}
+	}
+}
@@ -0,0 +1,495 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Формат CSV инвентаря: name,quantity,unit,price_per_unit (id при импорте игнорируется).
+// Формат CSV меню: одна строка на строку рецепта, строки одного блюда идут подряд
+// под одним name; поля блюда берутся из первой строки. category и allergens
+// перечисляются через "|". Блюдо без рецепта — одна строка с пустым ingredient.
+// Размеры, модификаторы, customization_options и metadata через CSV не переносятся.
+var (
+	inventoryCSVColumns = []string{"name", "quantity", "unit", "price_per_unit"}
+	menuCSVColumns      = []string{"name", "description", "price", "category", "allergens", "size", "ingredient", "quantity_required"}
+	validUnits          = []string{"grams", "ml", "pcs"}
+	validSizes          = []string{"small", "medium", "large"}
+)
+
+const csvListSeparator = "|"
+
+// csvRows читает CSV с заголовком и возвращает строки как map колонка -> значение.
+func csvRows(r io.Reader, required []string) ([]map[string]string, []models.CSVRowError, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, []models.CSVRowError{{Row: 1, Message: "файл пуст"}}, nil
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("не удалось прочитать заголовок CSV: %v", err)
+	}
+
+	// Excel сохраняет UTF-8 с BOM в начале первой колонки
+	index := make(map[string]int)
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	var rowErrors []models.CSVRowError
+	for _, col := range required {
+		if _, ok := index[col]; !ok {
+			rowErrors = append(rowErrors, models.CSVRowError{Row: 1, Column: col, Message: "нет колонки"})
+		}
+	}
+	if len(rowErrors) > 0 {
+		return nil, rowErrors, nil
+	}
+
+	var rows []map[string]string
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, append(rowErrors, models.CSVRowError{Row: line, Message: err.Error()}), nil
+		}
+
+		row := make(map[string]string, len(required))
+		for _, col := range required {
+			if i := index[col]; i < len(record) {
+				row[col] = strings.TrimSpace(record[i])
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		rowErrors = append(rowErrors, models.CSVRowError{Row: 2, Message: "нет строк с данными"})
+	}
+	return rows, rowErrors, nil
+}
+
+func oneOf(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func splitCSVList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, csvListSeparator) {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// copyRows загружает строки через COPY FROM STDIN.
+func copyRows(tx *sql.Tx, table string, columns []string, rows [][]interface{}) error {
+	stmt, err := tx.Prepare(pq.CopyIn(table, columns...))
+	if err != nil {
+		return fmt.Errorf("не удалось начать COPY в %s: %v", table, err)
+	}
+	for _, row := range rows {
+		if _, err := stmt.Exec(row...); err != nil {
+			stmt.Close()
+			return fmt.Errorf("ошибка COPY в %s: %v", table, err)
+		}
+	}
+	if _, err := stmt.Exec(); err != nil {
+		stmt.Close()
+		return fmt.Errorf("ошибка завершения COPY в %s: %v", table, err)
+	}
+	return stmt.Close()
+}
+
+// INVENTORY ---------------------------------------------------------------------------
+
+// ImportInventoryCSV проверяет все строки и, если ошибок нет, загружает их одним COPY.
+// Название должно быть уникальным и в файле, и среди неархивных ингредиентов,
+// потому что CSV меню ссылается на ингредиенты по названию.
+func ImportInventoryCSV(r io.Reader) (models.CSVImportResult, error) {
+	var result models.CSVImportResult
+
+	rows, rowErrors, err := csvRows(r, inventoryCSVColumns)
+	if err != nil || len(rowErrors) > 0 {
+		result.Errors = rowErrors
+		return result, err
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return result, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	existing, err := inventoryIDsByName(dbConn)
+	if err != nil {
+		return result, err
+	}
+
+	seen := make(map[string]int)
+	values := make([][]interface{}, 0, len(rows))
+	for i, row := range rows {
+		line := i + 2
+		fail := func(column, format string, args ...interface{}) {
+			rowErrors = append(rowErrors, models.CSVRowError{Row: line, Column: column, Message: fmt.Sprintf(format, args...)})
+		}
+
+		name := row["name"]
+		key := strings.ToLower(name)
+		if name == "" {
+			fail("name", "название обязательно")
+		} else if first, ok := seen[key]; ok {
+			fail("name", "повторяет строку %d", first)
+		} else if _, ok := existing[key]; ok {
+			fail("name", "ингредиент %q уже есть в инвентаре", name)
+		}
+		seen[key] = line
+
+		quantity, err := models.ParseQuantity(row["quantity"])
+		if err != nil {
+			fail("quantity", "%v", err)
+		} else if quantity <= 0 {
+			fail("quantity", "количество должно быть больше 0")
+		}
+
+		unit := strings.ToLower(row["unit"])
+		if !oneOf(validUnits, unit) {
+			fail("unit", "единица должна быть одной из: %s", strings.Join(validUnits, ", "))
+		}
+
+		price, err := strconv.ParseFloat(row["price_per_unit"], 64)
+		if err != nil || price <= 0 {
+			fail("price_per_unit", "цена должна быть числом больше 0")
+		}
+
+		values = append(values, []interface{}{name, quantity, unit, price})
+	}
+	if len(rowErrors) > 0 {
+		result.Errors = rowErrors
+		return result, nil
+	}
+
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return result, fmt.Errorf("не удалось начать транзакцию: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := copyRows(tx, "inventory", inventoryCSVColumns, values); err != nil {
+		return result, err
+	}
+
+	// COPY не возвращает id, поэтому начальные остатки в журнал пишутся по названиям
+	names := make([]string, 0, len(seen))
+	for key := range seen {
+		names = append(names, key)
+	}
+	_, err = tx.Exec(`INSERT INTO inventory_transactions (inventory_id, change_amount, reason, unit_cost, reference_type)
+					  SELECT id, quantity, 'Opening balance (CSV import)', price_per_unit, $2
+					  FROM inventory WHERE archived_at IS NULL AND LOWER(name) = ANY($1)`,
+		pq.Array(names), ReferenceOpening)
+	if err != nil {
+		return result, fmt.Errorf("не удалось записать начальные остатки: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return result, fmt.Errorf("не удалось завершить импорт: %v", err)
+	}
+
+	result.Imported = len(values)
+	return result, nil
+}
+
+// ExportInventoryCSV выгружает неархивные ингредиенты в формате, который принимает импорт.
+func ExportInventoryCSV(w io.Writer) error {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	rows, err := dbConn.Query(`SELECT id, name, quantity, unit, price_per_unit FROM inventory
+							   WHERE archived_at IS NULL ORDER BY id`)
+	if err != nil {
+		return fmt.Errorf("не удалось получить инвентарь: %v", err)
+	}
+	defer rows.Close()
+
+	writer := csv.NewWriter(w)
+	writer.Write(append([]string{"id"}, inventoryCSVColumns...))
+	for rows.Next() {
+		var id int
+		var name string
+		var quantity models.Quantity
+		var unit, price sql.NullString
+		if err := rows.Scan(&id, &name, &quantity, &unit, &price); err != nil {
+			return fmt.Errorf("ошибка при сканировании строки: %v", err)
+		}
+		writer.Write([]string{strconv.Itoa(id), name, quantity.String(), unit.String, price.String})
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("ошибка при итерации по строкам: %v", err)
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// inventoryIDsByName — неархивные ингредиенты по названию в нижнем регистре.
+func inventoryIDsByName(q queryer) (map[string]int, error) {
+	rows, err := q.Query(`SELECT id, name FROM inventory WHERE archived_at IS NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить инвентарь: %v", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]int)
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании ингредиента: %v", err)
+		}
+		result[strings.ToLower(name)] = id
+	}
+	return result, rows.Err()
+}
+
+// MENU --------------------------------------------------------------------------------
+
+// ImportMenuCSV проверяет все строки, резервирует ID блюд из последовательности
+// и загружает блюда и рецепты двумя COPY в одной транзакции.
+func ImportMenuCSV(r io.Reader) (models.CSVImportResult, error) {
+	var result models.CSVImportResult
+
+	rows, rowErrors, err := csvRows(r, menuCSVColumns)
+	if err != nil || len(rowErrors) > 0 {
+		result.Errors = rowErrors
+		return result, err
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return result, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	ingredients, err := inventoryIDsByName(dbConn)
+	if err != nil {
+		return result, err
+	}
+	existing := make(map[string]bool)
+	nameRows, err := dbConn.Query(`SELECT LOWER(name) FROM menu_items WHERE archived_at IS NULL`)
+	if err != nil {
+		return result, fmt.Errorf("не удалось получить меню: %v", err)
+	}
+	for nameRows.Next() {
+		var name string
+		if err := nameRows.Scan(&name); err != nil {
+			nameRows.Close()
+			return result, fmt.Errorf("ошибка при сканировании блюда: %v", err)
+		}
+		existing[name] = true
+	}
+	nameRows.Close()
+	if err := nameRows.Err(); err != nil {
+		return result, fmt.Errorf("ошибка при итерации по меню: %v", err)
+	}
+
+	type recipeLine struct {
+		ingredientID int
+		quantity     models.Quantity
+	}
+	type menuRow struct {
+		item  models.MenuItem
+		lines []recipeLine
+	}
+
+	var items []*menuRow
+	seen := make(map[string]int) // название -> строка, где блюдо началось
+	var current *menuRow
+	var currentKey string
+	for i, row := range rows {
+		line := i + 2
+		fail := func(column, format string, args ...interface{}) {
+			rowErrors = append(rowErrors, models.CSVRowError{Row: line, Column: column, Message: fmt.Sprintf(format, args...)})
+		}
+
+		name := row["name"]
+		key := strings.ToLower(name)
+		if name == "" {
+			fail("name", "название обязательно")
+			continue
+		}
+
+		// Новое блюдо начинается, когда меняется name
+		if current == nil || key != currentKey {
+			if first, ok := seen[key]; ok {
+				fail("name", "строки блюда должны идти подряд (блюдо начато в строке %d)", first)
+				continue
+			}
+			if existing[key] {
+				fail("name", "блюдо %q уже есть в меню", name)
+			}
+			seen[key] = line
+
+			current = &menuRow{item: models.MenuItem{
+				Name:        name,
+				Description: row["description"],
+				Category:    splitCSVList(row["category"]),
+				Allergens:   splitCSVList(row["allergens"]),
+				Size:        strings.ToLower(row["size"]),
+			}}
+			currentKey = key
+			items = append(items, current)
+
+			price, err := strconv.ParseFloat(row["price"], 64)
+			if err != nil || price <= 0 {
+				fail("price", "цена должна быть числом больше 0")
+			}
+			current.item.Price = price
+			if current.item.Size != "" && !oneOf(validSizes, current.item.Size) {
+				fail("size", "размер должен быть одним из: %s", strings.Join(validSizes, ", "))
+			}
+		}
+
+		if row["ingredient"] == "" {
+			if row["quantity_required"] != "" {
+				fail("ingredient", "указано количество без ингредиента")
+			}
+			continue
+		}
+		ingredientID, ok := ingredients[strings.ToLower(row["ingredient"])]
+		if !ok {
+			fail("ingredient", "ингредиент %q не найден в инвентаре", row["ingredient"])
+		}
+		quantity, err := models.ParseQuantity(row["quantity_required"])
+		if err != nil {
+			fail("quantity_required", "%v", err)
+		} else if quantity <= 0 {
+			fail("quantity_required", "количество должно быть больше 0")
+		}
+		for _, l := range current.lines {
+			if ok && l.ingredientID == ingredientID {
+				fail("ingredient", "ингредиент %q уже есть в рецепте", row["ingredient"])
+			}
+		}
+		current.lines = append(current.lines, recipeLine{ingredientID: ingredientID, quantity: quantity})
+	}
+	if len(rowErrors) > 0 {
+		result.Errors = rowErrors
+		return result, nil
+	}
+
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return result, fmt.Errorf("не удалось начать транзакцию: %v", err)
+	}
+	defer tx.Rollback()
+
+	idRows, err := tx.Query(`SELECT nextval(pg_get_serial_sequence('menu_items', 'id')) FROM generate_series(1, $1)`, len(items))
+	if err != nil {
+		return result, fmt.Errorf("не удалось зарезервировать ID блюд: %v", err)
+	}
+	for i := 0; idRows.Next(); i++ {
+		if err := idRows.Scan(&items[i].item.ID); err != nil {
+			idRows.Close()
+			return result, fmt.Errorf("ошибка при чтении ID блюда: %v", err)
+		}
+	}
+	idRows.Close()
+	if err := idRows.Err(); err != nil {
+		return result, fmt.Errorf("ошибка при резервировании ID блюд: %v", err)
+	}
+
+	menuValues := make([][]interface{}, 0, len(items))
+	var recipeValues [][]interface{}
+	for _, m := range items {
+		menuValues = append(menuValues, []interface{}{m.item.ID, m.item.Name, m.item.Description, m.item.Price,
+			pq.Array(m.item.Category), pq.Array(m.item.Allergens), nullableSize(m.item.Size)})
+		for _, l := range m.lines {
+			recipeValues = append(recipeValues, []interface{}{m.item.ID, l.ingredientID, l.quantity})
+		}
+	}
+
+	err = copyRows(tx, "menu_items", []string{"id", "name", "description", "price", "category", "allergens", "size"}, menuValues)
+	if err != nil {
+		return result, err
+	}
+	err = copyRows(tx, "menu_item_ingredients", []string{"menu_item_id", "ingredient_id", "quantity_required"}, recipeValues)
+	if err != nil {
+		return result, err
+	}
+	if err := tx.Commit(); err != nil {
+		return result, fmt.Errorf("не удалось завершить импорт: %v", err)
+	}
+
+	result.Imported = len(items)
+	return result, nil
+}
+
+// ExportMenuCSV выгружает неархивные блюда с базовыми рецептами в формате импорта.
+func ExportMenuCSV(w io.Writer) error {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	rows, err := dbConn.Query(`
+		SELECT mi.name, COALESCE(mi.description, ''), mi.price,
+			array_to_string(COALESCE(mi.category, '{}'), '|'), array_to_string(COALESCE(mi.allergens, '{}'), '|'),
+			COALESCE(mi.size::TEXT, ''), COALESCE(i.name, ''), mii.quantity_required
+		FROM menu_items mi
+		LEFT JOIN menu_item_ingredients mii ON mii.menu_item_id = mi.id
+		LEFT JOIN inventory i ON i.id = mii.ingredient_id
+		WHERE mi.archived_at IS NULL
+		ORDER BY mi.id, mii.id`)
+	if err != nil {
+		return fmt.Errorf("не удалось получить меню: %v", err)
+	}
+	defer rows.Close()
+
+	writer := csv.NewWriter(w)
+	writer.Write(menuCSVColumns)
+	for rows.Next() {
+		var name, description, price, category, allergens, size, ingredient string
+		var quantity sql.NullString
+		err := rows.Scan(&name, &description, &price, &category, &allergens, &size, &ingredient, &quantity)
+		if err != nil {
+			return fmt.Errorf("ошибка при сканировании строки: %v", err)
+		}
+
+		qty := ""
+		if quantity.Valid {
+			var q models.Quantity
+			if err := q.Scan(quantity.String); err != nil {
+				return fmt.Errorf("ошибка при сканировании количества: %v", err)
+			}
+			qty = q.String()
+		}
+		writer.Write([]string{name, description, price, category, allergens, size, ingredient, qty})
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("ошибка при итерации по строкам: %v", err)
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,10 @@
+package repositories
+
+import "errors"
+
+// Ошибки, по которым обработчики выбирают HTTP-статус. Оборачиваются через %w.
+var (
+	ErrNotFound = errors.New("не найдено")
+	ErrConflict = errors.New("конфликт")
+	ErrStale    = errors.New("версия устарела")
+)
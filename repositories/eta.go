@@ -0,0 +1,268 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// statusTimeline — первый момент каждого статуса заказа. Записи pending в истории
+// может не быть (заказ создаётся сразу в pending), тогда берётся order_date.
+// where ограничивает заказы до группировки, чтобы не собирать всю историю.
+func statusTimeline(where string) string {
+	return `
+	timeline AS (
+		SELECT o.id AS order_id, o.order_date,
+			COALESCE(MIN(h.changed_at) FILTER (WHERE h.status = 'pending'), o.order_date) AS pending_at,
+			MIN(h.changed_at) FILTER (WHERE h.status = 'preparing') AS preparing_at,
+			MIN(h.changed_at) FILTER (WHERE h.status = 'completed') AS completed_at
+		FROM orders o
+		LEFT JOIN order_status_history h ON h.order_id = o.id
+		` + where + `
+		GROUP BY o.id, o.order_date
+	)`
+}
+
+// historyTimeline — заказы, которые начали готовиться или были выданы
+// за последние $1 дней.
+var historyTimeline = statusTimeline(`WHERE EXISTS (
+			SELECT 1 FROM order_status_history w
+			WHERE w.order_id = o.id AND w.status IN ('preparing', 'completed')
+			  AND w.changed_at >= NOW() - make_interval(days => $1))`)
+
+// queueTimeline — заказ $1 и все заказы в работе.
+var queueTimeline = statusTimeline(`WHERE o.id = $1 OR o.status IN ('pending', 'preparing')`)
+
+const (
+	etaHistoryDays     = 30
+	etaMinItemSamples  = 5
+	etaMinWaitSamples  = 5
+	etaDefaultPrepSecs = 300
+)
+
+type prepStat struct {
+	avg     float64
+	samples int
+}
+
+// prepEstimator оценивает время приготовления заказа по составу.
+type prepEstimator struct {
+	byItem map[int]prepStat
+	global prepStat
+}
+
+func loadPrepEstimator(q queryer) (prepEstimator, error) {
+	e := prepEstimator{byItem: make(map[int]prepStat)}
+
+	rows, err := q.Query(`WITH`+historyTimeline+`
+		SELECT oi.menu_item_id, AVG(EXTRACT(EPOCH FROM t.completed_at - t.preparing_at)), COUNT(DISTINCT t.order_id)
+		FROM timeline t
+		JOIN order_items oi ON oi.order_id = t.order_id
+		WHERE t.preparing_at IS NOT NULL AND t.completed_at > t.preparing_at
+		  AND t.completed_at >= NOW() - make_interval(days => $1)
+		GROUP BY oi.menu_item_id`, etaHistoryDays)
+	if err != nil {
+		return e, fmt.Errorf("ошибка при расчёте времени приготовления: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var s prepStat
+		if err := rows.Scan(&id, &s.avg, &s.samples); err != nil {
+			return e, fmt.Errorf("ошибка при сканировании статистики: %v", err)
+		}
+		e.byItem[id] = s
+	}
+	if err := rows.Err(); err != nil {
+		return e, fmt.Errorf("ошибка при итерации по статистике: %v", err)
+	}
+
+	var avg sql.NullFloat64
+	err = q.QueryRow(`WITH`+historyTimeline+`
+		SELECT AVG(EXTRACT(EPOCH FROM t.completed_at - t.preparing_at)), COUNT(*)
+		FROM timeline t
+		WHERE t.preparing_at IS NOT NULL AND t.completed_at > t.preparing_at
+		  AND t.completed_at >= NOW() - make_interval(days => $1)`, etaHistoryDays).Scan(&avg, &e.global.samples)
+	if err != nil {
+		return e, fmt.Errorf("ошибка при расчёте среднего времени: %v", err)
+	}
+	e.global.avg = avg.Float64
+
+	return e, nil
+}
+
+// waitStat — наблюдаемое ожидание pending→preparing в пересчёте на одно место
+// в очереди: сколько в среднем ждёт заказ за каждый pending-заказ перед ним и за себя.
+type waitStat struct {
+	perSlot float64
+	samples int
+}
+
+// loadWaitStat считает ожидание по истории: для каждого заказа за окно берётся
+// время от pending до preparing и число заказов, которые в момент его создания
+// ещё ждали своей очереди перед ним.
+func loadWaitStat(q queryer) (waitStat, error) {
+	var total, slots sql.NullFloat64
+	var s waitStat
+	err := q.QueryRow(`WITH`+historyTimeline+`
+		SELECT SUM(EXTRACT(EPOCH FROM t.preparing_at - t.pending_at)),
+			SUM(1 + (SELECT COUNT(*) FROM timeline p
+			         WHERE (p.pending_at, p.order_id) < (t.pending_at, t.order_id)
+			           AND p.preparing_at > t.pending_at)),
+			COUNT(*)
+		FROM timeline t
+		WHERE t.preparing_at IS NOT NULL AND t.preparing_at >= t.pending_at
+		  AND t.preparing_at >= NOW() - make_interval(days => $1)`, etaHistoryDays).Scan(&total, &slots, &s.samples)
+	if err != nil {
+		return s, fmt.Errorf("ошибка при расчёте времени ожидания: %v", err)
+	}
+	if slots.Float64 > 0 {
+		s.perSlot = total.Float64 / slots.Float64
+	}
+	return s, nil
+}
+
+// estimate берёт самое долгое блюдо заказа (напитки готовятся параллельно),
+// если по нему достаточно данных, иначе общее среднее, иначе значение по умолчанию.
+func (e prepEstimator) estimate(menuItemIDs []int64) (float64, string, int) {
+	best, samples := 0.0, 0
+	for _, id := range menuItemIDs {
+		s, ok := e.byItem[int(id)]
+		if ok && s.samples >= etaMinItemSamples && s.avg > best {
+			best, samples = s.avg, s.samples
+		}
+	}
+	if best > 0 {
+		return best, "menu_item", samples
+	}
+	if e.global.samples > 0 {
+		return e.global.avg, "global", e.global.samples
+	}
+	return etaDefaultPrepSecs, "default", 0
+}
+
+// GetOrderETA оценивает готовность заказа: сколько он прождёт в очереди
+// (по истории ожиданий или по заказам впереди с учётом числа бариста) и сколько
+// займёт сам заказ.
+func GetOrderETA(idStr string) (models.OrderETA, error) {
+	orderID, err := strconv.Atoi(idStr)
+	if err != nil {
+		return models.OrderETA{}, fmt.Errorf("неверный формат ID: %v", err)
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return models.OrderETA{}, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	estimator, err := loadPrepEstimator(dbConn)
+	if err != nil {
+		return models.OrderETA{}, err
+	}
+	wait, err := loadWaitStat(dbConn)
+	if err != nil {
+		return models.OrderETA{}, err
+	}
+
+	// Сам заказ и все заказы в очереди впереди него, в порядке FIFO
+	rows, err := dbConn.Query(`WITH`+queueTimeline+`
+		SELECT o.id, o.status, COALESCE(t.preparing_at, o.claimed_at), o.assigned_staff_id,
+			COALESCE((SELECT array_agg(oi.menu_item_id) FROM order_items oi WHERE oi.order_id = o.id), ARRAY[]::INTEGER[])
+		FROM orders o
+		JOIN timeline t ON t.order_id = o.id
+		WHERE o.id = $1
+		   OR (o.status IN ('pending', 'preparing')
+		       AND (o.order_date, o.id) < (SELECT order_date, id FROM orders WHERE id = $1))
+		ORDER BY o.order_date, o.id`, orderID)
+	if err != nil {
+		return models.OrderETA{}, fmt.Errorf("ошибка при получении очереди: %v", err)
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	eta := models.OrderETA{OrderID: orderID}
+	found := false
+	var aheadSeconds float64
+	pendingAhead := 0
+	staff := make(map[int64]bool)
+
+	for rows.Next() {
+		var id int
+		var status string
+		var preparingAt sql.NullTime
+		var staffID sql.NullInt64
+		var items []int64
+		if err := rows.Scan(&id, &status, &preparingAt, &staffID, pq.Array(&items)); err != nil {
+			return models.OrderETA{}, fmt.Errorf("ошибка при сканировании очереди: %v", err)
+		}
+
+		prep, basis, samples := estimator.estimate(items)
+		remaining := prep
+		if status == "preparing" && preparingAt.Valid {
+			remaining = math.Max(0, prep-now.Sub(preparingAt.Time).Seconds())
+		}
+		if status == "preparing" && staffID.Valid {
+			staff[staffID.Int64] = true
+		}
+
+		if id != orderID {
+			eta.OrdersAhead++
+			aheadSeconds += remaining
+			if status == "pending" {
+				pendingAhead++
+			}
+			continue
+		}
+
+		found = true
+		eta.Status = status
+		eta.Basis = basis
+		eta.Samples = samples
+		if status == "pending" || status == "preparing" {
+			eta.EstimatedPrepSeconds = int(math.Round(remaining))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return models.OrderETA{}, fmt.Errorf("ошибка при итерации по очереди: %v", err)
+	}
+	if !found {
+		return models.OrderETA{}, fmt.Errorf("%w: заказ с ID %d", ErrNotFound, orderID)
+	}
+
+	if eta.Status != "pending" && eta.Status != "preparing" {
+		eta.OrdersAhead = 0
+		return eta, nil
+	}
+
+	// Очередь разбирают параллельно все бариста, которые сейчас что-то готовят
+	eta.Stations = len(staff)
+	if eta.Stations == 0 {
+		eta.Stations = 1
+	}
+	// Ожидание берём из наблюдаемых pending→preparing, если истории достаточно:
+	// в нём уже учтены и реальная скорость бариста, и время до взятия заказа.
+	// Иначе — сумма приготовления заказов впереди, разделённая между бариста.
+	if eta.Status == "pending" {
+		if wait.samples >= etaMinWaitSamples {
+			eta.EstimatedWaitSeconds = int(math.Round(wait.perSlot * float64(pendingAhead+1)))
+			eta.WaitBasis = "history"
+			eta.WaitSamples = wait.samples
+		} else {
+			eta.EstimatedWaitSeconds = int(math.Round(aheadSeconds / float64(eta.Stations)))
+			eta.WaitBasis = "queue"
+		}
+	}
+
+	readyAt := now.Add(time.Duration(eta.EstimatedWaitSeconds+eta.EstimatedPrepSeconds) * time.Second)
+	eta.EstimatedReadyAt = &readyAt
+
+	return eta, nil
+}
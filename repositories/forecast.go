@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"time"
+)
+
+// DefaultForecastHistoryDays — сколько полных дней истории заказов берётся
+// для прогноза: восемь недель дают по восемь наблюдений на каждый день недели.
+const DefaultForecastHistoryDays = 56
+
+// historicalUsageQuery раскладывает позиции заказов по рецептам так же, как
+// recipeQuery: рецепт варианта, иначе базовый рецепт × recipe_multiplier.
+// Модификаторы не учитываются — прогноз строится по рецептам блюд.
+// Расход группируется по моменту заказа, а не по дате: день определяется в Go
+// в том же часовом поясе, что и dayIndex, — у сессии БД он может быть другим.
+const historicalUsageQuery = `
+	SELECT r.ingredient_id, o.order_date, SUM(r.quantity_required * oi.quantity)
+	FROM order_items oi
+	JOIN orders o ON o.id = oi.order_id
+	CROSS JOIN LATERAL (
+		SELECT vi.ingredient_id, vi.quantity_required
+		FROM menu_item_variant_ingredients vi
+		WHERE vi.variant_id = oi.variant_id
+		UNION ALL
+		SELECT mii.ingredient_id,
+			mii.quantity_required * COALESCE(
+				(SELECT v.recipe_multiplier FROM menu_item_variants v WHERE v.id = oi.variant_id), 1)
+		FROM menu_item_ingredients mii
+		WHERE mii.menu_item_id = oi.menu_item_id
+		  AND NOT EXISTS (SELECT 1 FROM menu_item_variant_ingredients vi WHERE vi.variant_id = oi.variant_id)
+	) r
+	WHERE o.status <> 'canceled' AND o.order_date >= $1 AND o.order_date < $2
+	GROUP BY r.ingredient_id, o.order_date`
+
+// GetIngredientForecast прогнозирует расход каждого активного ингредиента
+// на days дней начиная с сегодняшнего и дату, когда при текущем остатке
+// он закончится.
+func GetIngredientForecast(days, historyDays int) ([]models.IngredientForecast, error) {
+	if historyDays <= 0 {
+		historyDays = DefaultForecastHistoryDays
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	historyStart := today.AddDate(0, 0, -historyDays)
+
+	rows, err := dbConn.Query(historicalUsageQuery, historyStart, today)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении истории расхода: %v", err)
+	}
+	defer rows.Close()
+
+	dayIndex := make(map[string]int, historyDays)
+	for i := 0; i < historyDays; i++ {
+		dayIndex[historyStart.AddDate(0, 0, i).Format("2006-01-02")] = i
+	}
+
+	history := make(map[int][]float64)
+	for rows.Next() {
+		var ingredientID int
+		var orderedAt time.Time
+		var used models.Quantity
+		if err := rows.Scan(&ingredientID, &orderedAt, &used); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании истории расхода: %v", err)
+		}
+		i, ok := dayIndex[orderedAt.In(now.Location()).Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		if history[ingredientID] == nil {
+			history[ingredientID] = make([]float64, historyDays)
+		}
+		history[ingredientID][i] += used.Float64()
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по истории расхода: %v", err)
+	}
+
+	items, err := dbConn.Query(`SELECT id, name, COALESCE(unit::TEXT, ''), quantity FROM inventory
+								WHERE archived_at IS NULL ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении инвентаря: %v", err)
+	}
+	defer items.Close()
+
+	forecasts := []models.IngredientForecast{}
+	for items.Next() {
+		var f models.IngredientForecast
+		if err := items.Scan(&f.InventoryID, &f.Name, &f.Unit, &f.Quantity); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании инвентаря: %v", err)
+		}
+
+		daily := utils.ForecastDemand(history[f.InventoryID], historyStart, today, days, utils.ForecastSmoothing)
+		projectStockOut(&f, daily, today)
+		forecasts = append(forecasts, f)
+	}
+
+	return forecasts, items.Err()
+}
+
+// projectStockOut заполняет дневной прогноз, итоги и дату исчерпания остатка.
+func projectStockOut(f *models.IngredientForecast, daily []float64, from time.Time) {
+	f.Daily = make([]models.ForecastDay, len(daily))
+	remaining := f.Quantity
+	for j, v := range daily {
+		day := from.AddDate(0, 0, j).Format("2006-01-02")
+		q := models.QuantityFromFloat64(v)
+		f.Daily[j] = models.ForecastDay{Date: day, Quantity: q}
+		f.ProjectedConsumption += q
+		remaining -= q
+
+		if f.StockOutDate == nil && (remaining < 0 || (remaining == 0 && (q > 0 || f.Quantity <= 0))) {
+			j := j
+			f.StockOutDate = &day
+			f.DaysUntilStockOut = &j
+		}
+	}
+	f.ProjectedRemaining = remaining
+	if len(daily) > 0 {
+		f.AvgDailyUsage = f.ProjectedConsumption.Div(len(daily))
+	}
+}
@@ -0,0 +1,219 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+	"math"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+// CreateGoodsReceipt проводит поступление в одной транзакции: сохраняет документ,
+// увеличивает остатки (quantity = quantity + n, без перезаписи параллельных списаний),
+// пересчитывает price_per_unit как средневзвешенную цену, заводит партию на каждую
+// строку и пишет журнал движений.
+// Если остаток был нулевым или отрицательным, цена берётся из поступления.
+func CreateGoodsReceipt(receipt models.GoodsReceipt) (models.GoodsReceipt, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return receipt, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return receipt, fmt.Errorf("не удалось начать транзакцию: %v", err)
+	}
+	defer tx.Rollback()
+
+	receipt, err = createGoodsReceipt(tx, receipt)
+	if err != nil {
+		return receipt, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return receipt, fmt.Errorf("не удалось провести поступление: %v", err)
+	}
+	return receipt, nil
+}
+
+// createGoodsReceipt проводит поступление внутри уже открытой транзакции.
+func createGoodsReceipt(tx queryer, receipt models.GoodsReceipt) (models.GoodsReceipt, error) {
+	receivedAt := sql.NullTime{Time: receipt.ReceivedAt, Valid: !receipt.ReceivedAt.IsZero()}
+	err := tx.QueryRow(`INSERT INTO goods_receipts (supplier, reference, note, received_at, purchase_order_id)
+					   VALUES (NULLIF($1, ''), NULLIF($2, ''), NULLIF($3, ''), COALESCE($4, NOW()), $5)
+					   RETURNING id, received_at`,
+		receipt.Supplier, receipt.Reference, receipt.Note, receivedAt, receipt.PurchaseOrderID).Scan(&receipt.ID, &receipt.ReceivedAt)
+	if err != nil {
+		return receipt, fmt.Errorf("не удалось создать поступление: %v", err)
+	}
+
+	// Блокируем все строки inventory сразу и по возрастанию id, как и при списании
+	// по заказу: иначе две приёмки с одними ингредиентами в разном порядке
+	// могут взаимно заблокироваться
+	ids := make([]int64, 0, len(receipt.Lines))
+	for _, line := range receipt.Lines {
+		ids = append(ids, int64(line.InventoryID))
+	}
+	if _, err := tx.Exec(`SELECT id FROM inventory WHERE id = ANY($1) ORDER BY id FOR UPDATE`, pq.Array(ids)); err != nil {
+		return receipt, fmt.Errorf("ошибка при блокировке ингредиентов: %v", err)
+	}
+
+	var total float64
+	for i := range receipt.Lines {
+		line := &receipt.Lines[i]
+
+		var archived bool
+		err := tx.QueryRow(`SELECT name, archived_at IS NOT NULL FROM inventory WHERE id = $1`,
+			line.InventoryID).Scan(&line.Name, &archived)
+		if err == sql.ErrNoRows {
+			return receipt, fmt.Errorf("%w: ингредиент с ID %d", ErrNotFound, line.InventoryID)
+		} else if err != nil {
+			return receipt, fmt.Errorf("ошибка при проверке ингредиента #%d: %v", line.InventoryID, err)
+		}
+		if archived {
+			return receipt, fmt.Errorf("%w: ингредиент %s в архиве", ErrConflict, line.Name)
+		}
+
+		var lineID int
+		err = tx.QueryRow(`INSERT INTO goods_receipt_lines (receipt_id, inventory_id, quantity, unit_cost, expires_at, purchase_order_line_id)
+						   VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+			receipt.ID, line.InventoryID, line.Quantity, line.UnitCost, line.ExpiresAt, line.PurchaseOrderLineID).Scan(&lineID)
+		if err != nil {
+			return receipt, fmt.Errorf("не удалось сохранить строку поступления: %v", err)
+		}
+
+		_, err = tx.Exec(`
+			UPDATE inventory SET
+				price_per_unit = CASE
+					WHEN quantity > 0 AND price_per_unit IS NOT NULL
+						THEN ROUND((quantity * price_per_unit + $1::NUMERIC * $2::NUMERIC) / (quantity + $1::NUMERIC), 4)
+					ELSE $2::NUMERIC
+				END,
+				quantity = quantity + $1::NUMERIC,
+				version = version + 1,
+				last_updated = NOW()
+			WHERE id = $3`, line.Quantity, line.UnitCost, line.InventoryID)
+		if err != nil {
+			return receipt, fmt.Errorf("не удалось оприходовать ингредиент #%d: %v", line.InventoryID, err)
+		}
+
+		unitCost := line.UnitCost
+		err = createLot(tx, models.InventoryLot{
+			InventoryID:        line.InventoryID,
+			Quantity:           line.Quantity,
+			UnitCost:           &unitCost,
+			ReceivedAt:         receipt.ReceivedAt,
+			ExpiresAt:          line.ExpiresAt,
+			GoodsReceiptLineID: &lineID,
+		})
+		if err != nil {
+			return receipt, err
+		}
+
+		err = recordInventoryTransaction(tx, models.InventoryTransaction{
+			InventoryID:   line.InventoryID,
+			ChangeAmount:  line.Quantity,
+			UnitCost:      &unitCost,
+			Reason:        fmt.Sprintf("Goods receipt #%d", receipt.ID),
+			ReferenceType: ReferenceGoodsReceipt,
+			ReferenceID:   &receipt.ID,
+			// Поступление могли провести задним числом — журнал должен совпадать с документом
+			TransactionDate: receipt.ReceivedAt,
+		})
+		if err != nil {
+			return receipt, err
+		}
+
+		total += line.Quantity.Float64() * line.UnitCost
+	}
+	receipt.Total = math.Round(total*100) / 100
+
+	return receipt, nil
+}
+
+func GetGoodsReceipts() ([]models.GoodsReceipt, error) {
+	return getGoodsReceipts(0)
+}
+
+func GetGoodsReceiptByID(idStr string) (models.GoodsReceipt, error) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return models.GoodsReceipt{}, fmt.Errorf("неверный формат ID: %v", err)
+	}
+
+	receipts, err := getGoodsReceipts(id)
+	if err != nil {
+		return models.GoodsReceipt{}, err
+	}
+	if len(receipts) == 0 {
+		return models.GoodsReceipt{}, fmt.Errorf("%w: поступление с ID %d", ErrNotFound, id)
+	}
+	return receipts[0], nil
+}
+
+// getGoodsReceipts возвращает все поступления (id == 0) или одно, вместе со строками.
+func getGoodsReceipts(id int) ([]models.GoodsReceipt, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	rows, err := dbConn.Query(`SELECT id, COALESCE(supplier, ''), COALESCE(reference, ''), COALESCE(note, ''), received_at, purchase_order_id
+							   FROM goods_receipts WHERE $1 = 0 OR id = $1
+							   ORDER BY received_at DESC, id DESC`, id)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении поступлений: %v", err)
+	}
+	defer rows.Close()
+
+	var receipts []models.GoodsReceipt
+	var ids []int64
+	for rows.Next() {
+		var r models.GoodsReceipt
+		if err := rows.Scan(&r.ID, &r.Supplier, &r.Reference, &r.Note, &r.ReceivedAt, &r.PurchaseOrderID); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании поступления: %v", err)
+		}
+		receipts = append(receipts, r)
+		ids = append(ids, int64(r.ID))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по поступлениям: %v", err)
+	}
+
+	lineRows, err := dbConn.Query(`SELECT l.receipt_id, l.inventory_id, i.name, l.quantity, l.unit_cost, l.expires_at, l.purchase_order_line_id
+								   FROM goods_receipt_lines l JOIN inventory i ON i.id = l.inventory_id
+								   WHERE l.receipt_id = ANY($1) ORDER BY l.id`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении строк поступлений: %v", err)
+	}
+	defer lineRows.Close()
+
+	lines := make(map[int][]models.GoodsReceiptLine)
+	for lineRows.Next() {
+		var receiptID int
+		var l models.GoodsReceiptLine
+		if err := lineRows.Scan(&receiptID, &l.InventoryID, &l.Name, &l.Quantity, &l.UnitCost, &l.ExpiresAt, &l.PurchaseOrderLineID); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании строки поступления: %v", err)
+		}
+		lines[receiptID] = append(lines[receiptID], l)
+	}
+	if err := lineRows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по строкам поступлений: %v", err)
+	}
+
+	for i := range receipts {
+		receipts[i].Lines = lines[receipts[i].ID]
+		var total float64
+		for _, l := range receipts[i].Lines {
+			total += l.Quantity.Float64() * l.UnitCost
+		}
+		receipts[i].Total = math.Round(total*100) / 100
+	}
+
+	return receipts, nil
+}
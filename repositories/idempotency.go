@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+	"time"
+)
+
+// ReserveIdempotencyKey пытается занять ключ. Если ключ свободен, истёк или
+// занят запросом, который не сохранил ответ дольше lease (процесс упал),
+// он занимается под этот запрос и возвращается reserved == true вместе с
+// reservedAt — временем резервирования. Его нужно передать в SaveIdempotentResponse
+// и ReleaseIdempotencyKey: если ключ уже перехватил повтор, запоздавший исходный
+// запрос не тронет чужую резервацию. Иначе возвращается то, что сохранено по ключу ранее.
+func ReserveIdempotencyKey(scope, key, requestHash string, ttl, lease time.Duration) (bool, time.Time, models.IdempotentResponse, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return false, time.Time{}, models.IdempotentResponse{}, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	// Заодно убираем давно истёкшие ключи, чтобы таблица не росла
+	if _, err := dbConn.Exec(`DELETE FROM idempotency_keys WHERE expires_at < NOW() - INTERVAL '1 day'`); err != nil {
+		return false, time.Time{}, models.IdempotentResponse{}, fmt.Errorf("ошибка при очистке ключей: %v", err)
+	}
+
+	query := `INSERT INTO idempotency_keys (scope, key, request_hash, expires_at)
+			  VALUES ($1, $2, $3, NOW() + make_interval(secs => $4))
+			  ON CONFLICT (scope, key) DO UPDATE
+			  SET request_hash = EXCLUDED.request_hash, status_code = NULL, content_type = NULL,
+				  response_body = NULL, created_at = NOW(), expires_at = EXCLUDED.expires_at
+			  WHERE idempotency_keys.expires_at <= NOW()
+				 OR (idempotency_keys.status_code IS NULL AND idempotency_keys.created_at < NOW() - make_interval(secs => $5))
+			  RETURNING created_at`
+
+	var reservedAt time.Time
+	err = dbConn.QueryRow(query, scope, key, requestHash, ttl.Seconds(), lease.Seconds()).Scan(&reservedAt)
+	if err == nil {
+		return true, reservedAt, models.IdempotentResponse{}, nil
+	} else if err != sql.ErrNoRows {
+		return false, time.Time{}, models.IdempotentResponse{}, fmt.Errorf("ошибка при резервировании ключа: %v", err)
+	}
+
+	var stored models.IdempotentResponse
+	var status sql.NullInt64
+	var contentType sql.NullString
+	err = dbConn.QueryRow(`SELECT request_hash, status_code, content_type, response_body
+						   FROM idempotency_keys WHERE scope = $1 AND key = $2`, scope, key).
+		Scan(&stored.RequestHash, &status, &contentType, &stored.Body)
+	if err != nil {
+		return false, time.Time{}, models.IdempotentResponse{}, fmt.Errorf("ошибка при чтении ключа: %v", err)
+	}
+	stored.StatusCode = int(status.Int64)
+	stored.ContentType = contentType.String
+
+	return false, time.Time{}, stored, nil
+}
+
+// SaveIdempotentResponse сохраняет ответ, который будет повторяться для этого ключа.
+// Если резервацию reservedAt уже перехватил повтор, возвращается ErrConflict.
+func SaveIdempotentResponse(scope, key string, reservedAt time.Time, response models.IdempotentResponse) error {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	res, err := dbConn.Exec(`UPDATE idempotency_keys SET status_code = $1, content_type = $2, response_body = $3
+						  WHERE scope = $4 AND key = $5 AND created_at = $6 AND status_code IS NULL`,
+		response.StatusCode, response.ContentType, response.Body, scope, key, reservedAt)
+	if err != nil {
+		return fmt.Errorf("не удалось сохранить ответ по ключу: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: ключ %q уже занят другим запросом", ErrConflict, key)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey освобождает ключ, если запрос упал с ошибкой сервера,
+// чтобы повтор мог выполниться заново. Освобождается только своя резервация
+// reservedAt, пока по ней не сохранён ответ.
+func ReleaseIdempotencyKey(scope, key string, reservedAt time.Time) error {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	_, err = dbConn.Exec(`DELETE FROM idempotency_keys
+						  WHERE scope = $1 AND key = $2 AND created_at = $3 AND status_code IS NULL`, scope, key, reservedAt)
+	if err != nil {
+		return fmt.Errorf("не удалось освободить ключ: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+	"os"
+	"testing"
+	"time"
+)
+
+// Тест работает с настоящей БД (переменные DB_* как у сервера) и пропускается без неё.
+func TestIdempotencyTakeoverThenLateRelease(t *testing.T) {
+	if os.Getenv("DB_HOST") == "" {
+		t.Skip("DB_HOST не задан, тест с БД пропущен")
+	}
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Skipf("БД недоступна: %v", err)
+	}
+	defer dbConn.Close()
+
+	scope, key := "test", fmt.Sprintf("takeover-%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		dbConn.Exec(`DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2`, scope, key)
+	})
+
+	// Исходный запрос занимает ключ и «зависает»
+	reserved, first, _, err := ReserveIdempotencyKey(scope, key, "hash", time.Hour, time.Hour)
+	if err != nil || !reserved {
+		t.Fatalf("первое резервирование: reserved=%v, err=%v", reserved, err)
+	}
+
+	// Повтор после истечения lease перехватывает ключ
+	time.Sleep(10 * time.Millisecond)
+	reserved, retry, _, err := ReserveIdempotencyKey(scope, key, "hash", time.Hour, time.Millisecond)
+	if err != nil || !reserved {
+		t.Fatalf("перехват ключа: reserved=%v, err=%v", reserved, err)
+	}
+	if retry.Equal(first) {
+		t.Fatalf("перехват вернул ту же резервацию %v", first)
+	}
+
+	// Исходный запрос падает с 5xx и освобождает свою резервацию — чужую он трогать не должен
+	if err := ReleaseIdempotencyKey(scope, key, first); err != nil {
+		t.Fatalf("освобождение: %v", err)
+	}
+	err = SaveIdempotentResponse(scope, key, first, models.IdempotentResponse{StatusCode: 201})
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("сохранение по перехваченной резервации: ожидался ErrConflict, получено %v", err)
+	}
+
+	// Третья попытка видит, что повтор ещё выполняется, и не запускает действие снова
+	reserved, _, stored, err := ReserveIdempotencyKey(scope, key, "hash", time.Hour, time.Hour)
+	if err != nil {
+		t.Fatalf("третье резервирование: %v", err)
+	}
+	if reserved || stored.StatusCode != 0 {
+		t.Fatalf("третье резервирование: reserved=%v, status=%d; ожидалось, что ключ занят повтором", reserved, stored.StatusCode)
+	}
+
+	// Повтор сохраняет свой ответ, и дальше он повторяется
+	if err := SaveIdempotentResponse(scope, key, retry, models.IdempotentResponse{StatusCode: 201, Body: []byte("{}")}); err != nil {
+		t.Fatalf("сохранение ответа повтора: %v", err)
+	}
+	reserved, _, stored, err = ReserveIdempotencyKey(scope, key, "hash", time.Hour, time.Hour)
+	if err != nil || reserved || stored.StatusCode != 201 {
+		t.Fatalf("после сохранения: reserved=%v, status=%d, err=%v", reserved, stored.StatusCode, err)
+	}
+}
@@ -1,13 +1,13 @@
 package repositories
 
 import (
-	"database/sql"
 	"fmt"
 	"frappuccino/db"
+	"frappuccino/models"
 	"log"
 )
 
-func AddIngredientToMenu(menuItemID int, ingredientID int, quantityRequired int) error {
+func AddIngredientToMenu(menuItemID int, ingredientID int, quantityRequired models.Quantity) error {
 	// Подключаемся к базе данных
 	dbConn, err := db.InitDB()
 	if err != nil {
@@ -15,10 +15,14 @@ func AddIngredientToMenu(menuItemID int, ingredientID int, quantityRequired int)
 	}
 	defer dbConn.Close()
 
-	// Добавляем ингредиент в menu_item_ingredients
+	return addIngredientToMenu(dbConn, menuItemID, ingredientID, quantityRequired)
+}
+
+// addIngredientToMenu добавляет ингредиент в menu_item_ingredients.
+func addIngredientToMenu(q queryer, menuItemID int, ingredientID int, quantityRequired models.Quantity) error {
 	query := `INSERT INTO menu_item_ingredients (menu_item_id, ingredient_id, quantity_required) 
 			  VALUES ($1, $2, $3)`
-	_, err = dbConn.Exec(query, menuItemID, ingredientID, quantityRequired)
+	_, err := q.Exec(query, menuItemID, ingredientID, quantityRequired)
 	if err != nil {
 		return fmt.Errorf("error inserting ingredient into menu_item_ingredients: %v", err)
 	}
@@ -26,16 +30,11 @@ func AddIngredientToMenu(menuItemID int, ingredientID int, quantityRequired int)
 	return nil
 }
 
-func DeleteMenuItemDependencies(dbConn *sql.DB, menuItemID int) error {
+// DeleteMenuItemDependencies удаляет рецепт блюда перед его перезаписью.
+// Позиции заказов не трогаются: они — история продаж.
+func DeleteMenuItemDependencies(dbConn queryer, menuItemID int) error {
 	const logPrefix = "[DeleteMenuItemDependencies]"
 
-	log.Printf("%s Remove from order_items for menu_item_id = %d", logPrefix, menuItemID)
-	deleteOrderItemsQuery := `DELETE FROM order_items WHERE menu_item_id = $1`
-	if _, err := dbConn.Exec(deleteOrderItemsQuery, menuItemID); err != nil {
-		log.Printf("%s Error while deleting from order_items: %v", logPrefix, err)
-		return fmt.Errorf("error removing dependencies from order_items: %v", err)
-	}
-
 	log.Printf("%s Remove from menu_item_ingredients for menu_item_id = %d", logPrefix, menuItemID)
 	deleteIngredientsQuery := `DELETE FROM menu_item_ingredients WHERE menu_item_id = $1`
 	if _, err := dbConn.Exec(deleteIngredientsQuery, menuItemID); err != nil {
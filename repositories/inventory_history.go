@@ -0,0 +1,163 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// beginSnapshot открывает читающую транзакцию REPEATABLE READ: все запросы в ней
+// видят один снимок БД, и заказ, проведённый между чтением остатков и журнала,
+// не даёт ложного расхождения.
+func beginSnapshot(dbConn *sql.DB) (*sql.Tx, error) {
+	tx, err := dbConn.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("не удалось начать транзакцию: %v", err)
+	}
+	return tx, nil
+}
+
+// applyInventoryAsOf заменяет остатки на значения в момент asOf: от текущего
+// остатка отнимаются все движения журнала после asOf. Заодно проверяется, что
+// журнал объясняет остаток целиком и что при откате остаток нигде не уходит
+// в минус — иначе в журнале есть пропуски и результат помечается.
+func applyInventoryAsOf(q queryer, items []models.InventoryItem, asOf time.Time) error {
+	ids := make([]int64, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, int64(item.ID))
+	}
+
+	rows, err := q.Query(`
+		WITH totals AS (
+			SELECT inventory_id,
+				COALESCE(SUM(change_amount) FILTER (WHERE transaction_date > $1), 0) AS after_as_of,
+				SUM(change_amount) AS total
+			FROM inventory_transactions
+			WHERE inventory_id = ANY($2)
+			GROUP BY inventory_id
+		),
+		balances AS (
+			SELECT inventory_id, MIN(balance_before) AS min_balance
+			FROM (
+				SELECT t.inventory_id,
+					i.quantity - SUM(t.change_amount) OVER (
+						PARTITION BY t.inventory_id ORDER BY t.transaction_date DESC, t.id DESC) AS balance_before
+				FROM inventory_transactions t
+				JOIN inventory i ON i.id = t.inventory_id
+				WHERE t.inventory_id = ANY($2) AND t.transaction_date > $1
+			) x
+			GROUP BY inventory_id
+		)
+		SELECT t.inventory_id, t.after_as_of, t.total, COALESCE(b.min_balance, 0)
+		FROM totals t
+		LEFT JOIN balances b ON b.inventory_id = t.inventory_id`, asOf, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("ошибка при чтении журнала движений: %v", err)
+	}
+	defer rows.Close()
+
+	type ledgerTotals struct {
+		afterAsOf  models.Quantity
+		total      models.Quantity
+		minBalance models.Quantity
+	}
+	totals := make(map[int]ledgerTotals)
+	for rows.Next() {
+		var id int
+		var t ledgerTotals
+		if err := rows.Scan(&id, &t.afterAsOf, &t.total, &t.minBalance); err != nil {
+			return fmt.Errorf("ошибка при сканировании журнала движений: %v", err)
+		}
+		totals[id] = t
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("ошибка при итерации по журналу движений: %v", err)
+	}
+
+	for i := range items {
+		item := &items[i]
+		t, ok := totals[item.ID]
+		check := &models.LedgerCheck{UnexplainedQuantity: item.Quantity - t.total}
+
+		if !ok {
+			check.Issues = append(check.Issues, "в журнале нет движений по ингредиенту")
+		} else {
+			if check.UnexplainedQuantity != 0 {
+				check.Issues = append(check.Issues, fmt.Sprintf(
+					"остаток %s не объясняется журналом: сумма движений %s", item.Quantity, t.total))
+			}
+			if t.minBalance < 0 {
+				check.Issues = append(check.Issues, fmt.Sprintf(
+					"при откате журнала остаток уходит в минус (%s)", t.minBalance))
+			}
+		}
+		check.Consistent = len(check.Issues) == 0
+
+		item.Quantity -= t.afterAsOf
+		asOfCopy := asOf
+		item.AsOf = &asOfCopy
+		item.LedgerCheck = check
+	}
+	return nil
+}
+
+// GetInventoryItemsAsOf возвращает остатки на момент asOf (см. applyInventoryAsOf).
+func GetInventoryItemsAsOf(includeArchived bool, asOf time.Time) ([]models.InventoryItem, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	tx, err := beginSnapshot(dbConn)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	items, err := getInventoryItems(tx, includeArchived)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyInventoryAsOf(tx, items, asOf); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetInventoryItemAsOf возвращает остаток ингредиента на момент asOf.
+func GetInventoryItemAsOf(idStr string, asOf time.Time) (models.InventoryItem, error) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return models.InventoryItem{}, fmt.Errorf("ошибка при преобразовании ID: %v", err)
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return models.InventoryItem{}, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	tx, err := beginSnapshot(dbConn)
+	if err != nil {
+		return models.InventoryItem{}, err
+	}
+	defer tx.Rollback()
+
+	item, err := getInventoryItemByID(tx, id)
+	if err != nil {
+		return item, err
+	}
+
+	items := []models.InventoryItem{item}
+	if err := applyInventoryAsOf(tx, items, asOf); err != nil {
+		return item, err
+	}
+	return items[0], nil
+}
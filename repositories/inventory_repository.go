@@ -9,14 +9,20 @@ import (
 	"strconv"
 )
 
-func GetInventoryItems() ([]models.InventoryItem, error) {
+// GetInventoryItems возвращает остатки; архивные ингредиенты — только при includeArchived.
+func GetInventoryItems(includeArchived bool) ([]models.InventoryItem, error) {
 	dbConn, err := db.InitDB()
 	if err != nil {
 		return nil, fmt.Errorf("не удалось подключиться к БД: %v", err)
 	}
 	defer dbConn.Close()
 
-	rows, err := dbConn.Query(`SELECT id, name, quantity, unit, price_per_unit, last_updated FROM inventory`)
+	return getInventoryItems(dbConn, includeArchived)
+}
+
+func getInventoryItems(q queryer, includeArchived bool) ([]models.InventoryItem, error) {
+	rows, err := q.Query(`SELECT id, name, quantity, unit, price_per_unit, last_updated, version, archived_at, par_level, lead_time_days
+							   FROM inventory WHERE $1 OR archived_at IS NULL ORDER BY id`, includeArchived)
 	if err != nil {
 		return nil, fmt.Errorf("не удалось получить инвентарь: %v", err)
 	}
@@ -26,7 +32,7 @@ func GetInventoryItems() ([]models.InventoryItem, error) {
 
 	for rows.Next() {
 		var item models.InventoryItem
-		err := rows.Scan(&item.ID, &item.Name, &item.Quantity, &item.Unit, &item.PricePerUnit, &item.LastUpdated)
+		err := rows.Scan(&item.ID, &item.Name, &item.Quantity, &item.Unit, &item.PricePerUnit, &item.LastUpdated, &item.Version, &item.ArchivedAt, &item.ParLevel, &item.LeadTimeDays)
 		if err != nil {
 			return nil, fmt.Errorf("ошибка при сканировании строки: %v", err)
 		}
@@ -40,6 +46,7 @@ func GetInventoryItems() ([]models.InventoryItem, error) {
 	return items, nil
 }
 
+// CreateInventoryItems создаёт ингредиент и записывает начальный остаток в журнал.
 func CreateInventoryItems(item models.InventoryItem) (int, error) {
 	dbConn, err := db.InitDB()
 	if err != nil {
@@ -47,15 +54,37 @@ func CreateInventoryItems(item models.InventoryItem) (int, error) {
 	}
 	defer dbConn.Close()
 
-	query := `INSERT INTO inventory (name, quantity, unit, price_per_unit) VALUES ($1, $2, $3, $4) RETURNING id`
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("не удалось начать транзакцию: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO inventory (name, quantity, unit, price_per_unit, par_level, lead_time_days)
+			  VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
 
 	var id int
 
-	err = dbConn.QueryRow(query, item.Name, item.Quantity, item.Unit, item.PricePerUnit).Scan(&id)
+	err = tx.QueryRow(query, item.Name, item.Quantity, item.Unit, item.PricePerUnit, item.ParLevel, item.LeadTimeDays).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("не удалось создать элемент инвентаря: %v", err)
 	}
 
+	price := item.PricePerUnit
+	err = recordInventoryTransaction(tx, models.InventoryTransaction{
+		InventoryID:   id,
+		ChangeAmount:  item.Quantity,
+		UnitCost:      &price,
+		Reason:        "Opening balance",
+		ReferenceType: ReferenceOpening,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("не удалось создать элемент инвентаря: %v", err)
+	}
 	return id, nil
 }
 
@@ -72,13 +101,18 @@ func GetInventoryItemByID(idstr string) (models.InventoryItem, error) {
 	}
 	defer dbConn.Close()
 
+	return getInventoryItemByID(dbConn, idInt)
+}
+
+func getInventoryItemByID(q queryer, idInt int) (models.InventoryItem, error) {
 	var item models.InventoryItem
 
-	query := `SELECT id, name, quantity, unit, price_per_unit, last_updated FROM inventory WHERE id = $1`
-	err = dbConn.QueryRow(query, idInt).Scan(&item.ID, &item.Name, &item.Quantity, &item.Unit, &item.PricePerUnit, &item.LastUpdated)
+	query := `SELECT id, name, quantity, unit, price_per_unit, last_updated, version, archived_at, par_level, lead_time_days
+			  FROM inventory WHERE id = $1`
+	err := q.QueryRow(query, idInt).Scan(&item.ID, &item.Name, &item.Quantity, &item.Unit, &item.PricePerUnit, &item.LastUpdated, &item.Version, &item.ArchivedAt, &item.ParLevel, &item.LeadTimeDays)
 
 	if err == sql.ErrNoRows {
-		return models.InventoryItem{}, fmt.Errorf("инвентарь с таким ID не найден")
+		return models.InventoryItem{}, fmt.Errorf("%w: инвентарь с таким ID", ErrNotFound)
 	} else if err != nil {
 		return models.InventoryItem{}, fmt.Errorf("ошибка при получении данных: %v", err)
 	}
@@ -86,71 +120,243 @@ func GetInventoryItemByID(idstr string) (models.InventoryItem, error) {
 	return item, nil
 }
 
-func UpdateInventoryItem(idStr string, item models.InventoryItem) error {
+// UpdateInventoryItem обновляет элемент, только если его версия равна expectedVersion,
+// и возвращает новую версию. Ручное изменение количества пишется в журнал
+// как корректировка, чтобы остаток можно было восстановить на прошлую дату.
+func UpdateInventoryItem(idStr string, item models.InventoryItem, expectedVersion int) (int, error) {
 	idInt, err := strconv.Atoi(idStr)
 	if err != nil {
-		return fmt.Errorf("неправильный формат ID: %v", err)
+		return 0, fmt.Errorf("неправильный формат ID: %v", err)
 	}
 
 	dbConn, err := db.InitDB()
 	if err != nil {
-		return fmt.Errorf("не удалось подключиться к БД: %v", err)
+		return 0, fmt.Errorf("не удалось подключиться к БД: %v", err)
 	}
 
 	defer dbConn.Close()
 
-	query := `UPDATE inventory SET name=$1, quantity=$2, unit=$3, price_per_unit=$4, last_updated=NOW() WHERE id=$5`
-
-	result, err := dbConn.Exec(query, item.Name, item.Quantity, item.Unit, item.PricePerUnit, idInt)
+	tx, err := dbConn.Begin()
 	if err != nil {
-		return fmt.Errorf("ошибка при обновлении элемента меню: %v", err)
+		return 0, fmt.Errorf("не удалось начать транзакцию: %v", err)
+	}
+	defer tx.Rollback()
+
+	var current int
+	err = tx.QueryRow(`SELECT version FROM inventory WHERE id = $1 FOR UPDATE`, idInt).Scan(&current)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("%w: элемент инвентаря с ID %v", ErrNotFound, idInt)
+	} else if err != nil {
+		return 0, fmt.Errorf("ошибка при получении элемента инвентаря: %v", err)
+	}
+	if current != expectedVersion {
+		return 0, fmt.Errorf("%w: ожидалась версия %d, текущая %d", ErrStale, expectedVersion, current)
+	}
+
+	// Просроченные партии списываются до корректировки, иначе уменьшение
+	// остатка уйдёт на свежие партии, а просрочка спишется ещё раз.
+	// Версия уже проверена, строка заблокирована — списание её не нарушает.
+	if _, err := writeOffExpiredLots(tx, idInt); err != nil {
+		return 0, err
+	}
+
+	var before models.Quantity
+	if err := tx.QueryRow(`SELECT quantity FROM inventory WHERE id = $1`, idInt).Scan(&before); err != nil {
+		return 0, fmt.Errorf("ошибка при получении элемента инвентаря: %v", err)
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	query := `UPDATE inventory SET name=$1, quantity=$2, unit=$3, price_per_unit=$4, par_level=$5, lead_time_days=$6,
+				last_updated=NOW(), version=version+1
+			  WHERE id=$7 RETURNING version`
+
+	var version int
+	err = tx.QueryRow(query, item.Name, item.Quantity, item.Unit, item.PricePerUnit, item.ParLevel, item.LeadTimeDays,
+		idInt).Scan(&version)
 	if err != nil {
-		return fmt.Errorf("ошибка получения количества обновленных строк: %v", err)
+		return 0, fmt.Errorf("ошибка при обновлении элемента инвентаря: %v", err)
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("элемент меню с ID %v не найден", idInt)
+	if delta := item.Quantity - before; delta != 0 {
+		if delta < 0 {
+			if err := consumeLots(tx, idInt, -delta); err != nil {
+				return 0, err
+			}
+		}
+		price := item.PricePerUnit
+		err = recordInventoryTransaction(tx, models.InventoryTransaction{
+			InventoryID:   idInt,
+			ChangeAmount:  delta,
+			UnitCost:      &price,
+			Reason:        "Manual adjustment",
+			ReferenceType: ReferenceAdjustment,
+		})
+		if err != nil {
+			return 0, err
+		}
 	}
 
-	return nil
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("ошибка при обновлении элемента инвентаря: %v", err)
+	}
+	return version, nil
 }
 
-func DeleteInventoryItem(idstr string) error {
+// DeleteInventoryItem удаляет ингредиент по плану из planInventoryDeletion.
+// Если ингредиент входит в рецепты, без force возвращается ErrConflict вместе с планом;
+// с force он убирается из рецептов. Ингредиент с историей движений архивируется,
+// а не удаляется, чтобы не потерять inventory_transactions.
+// При dryRun ничего не меняется и версия не проверяется.
+func DeleteInventoryItem(idstr string, expectedVersion int, force, dryRun bool) (models.InventoryDeletePlan, error) {
 	idInt, err := strconv.Atoi(idstr)
 	if err != nil {
-		return fmt.Errorf("ошибка при преобразовании ID: %v", err)
+		return models.InventoryDeletePlan{}, fmt.Errorf("ошибка при преобразовании ID: %v", err)
 	}
 
-	// Подключаемся к базе данных
 	dbConn, err := db.InitDB()
 	if err != nil {
-		log.Println("Не удалось подключиться к БД:", err)
-		return fmt.Errorf("не удалось подключиться к базе данных: %v", err)
+		return models.InventoryDeletePlan{}, fmt.Errorf("не удалось подключиться к базе данных: %v", err)
 	}
 	defer dbConn.Close()
 
-	deleteIngredientsQuery := `DELETE FROM menu_item_ingredients WHERE ingredient_id = $1`
-	_, err = dbConn.Exec(deleteIngredientsQuery, idInt)
+	tx, err := dbConn.Begin()
 	if err != nil {
-		return fmt.Errorf("не удалось удалить зависимости из menu_item_ingredients: %v", err)
+		return models.InventoryDeletePlan{}, fmt.Errorf("не удалось начать транзакцию: %v", err)
 	}
+	defer tx.Rollback()
 
-	query := `DELETE FROM inventory WHERE id = $1`
-	result, err := dbConn.Exec(query, idInt)
+	plan, current, err := planInventoryDeletion(tx, idInt)
 	if err != nil {
-		return fmt.Errorf("ошибка при удалении элемента инвентаря: %v", err)
+		return plan, err
+	}
+	plan.DryRun = dryRun
+	if dryRun {
+		return plan, nil
+	}
+
+	// Сначала проверяем версию, чтобы не трогать рецепты при устаревшем If-Match
+	if current != expectedVersion {
+		return plan, fmt.Errorf("%w: ожидалась версия %d, текущая %d", ErrStale, expectedVersion, current)
+	}
+	if len(plan.UsedIn) > 0 && !force {
+		return plan, fmt.Errorf("%w: ингредиент используется в %d рецептах, укажите force=true", ErrConflict, len(plan.UsedIn))
+	}
+	if plan.Action == "delete" && len(plan.BlockedBy) > 0 {
+		return plan, fmt.Errorf("%w: на ингредиент ссылаются %d документов поставщиков", ErrConflict, len(plan.BlockedBy))
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	dependencies := []string{
+		`DELETE FROM menu_item_ingredients WHERE ingredient_id = $1`,
+		`DELETE FROM menu_item_variant_ingredients WHERE ingredient_id = $1`,
+		`DELETE FROM modifier_ingredients WHERE ingredient_id = $1 OR replaces_ingredient_id = $1`,
+	}
+	for _, query := range dependencies {
+		if _, err := tx.Exec(query, idInt); err != nil {
+			return plan, fmt.Errorf("не удалось удалить ингредиент из рецептов: %v", err)
+		}
+	}
+
+	if plan.Action == "archive" {
+		_, err = tx.Exec(`UPDATE inventory SET archived_at = NOW(), version = version + 1 WHERE id = $1`, idInt)
+	} else {
+		_, err = tx.Exec(`DELETE FROM inventory WHERE id = $1`, idInt)
+	}
 	if err != nil {
-		return fmt.Errorf("ошибка получения количества удалённых строк: %v", err)
+		return plan, fmt.Errorf("ошибка при удалении элемента инвентаря: %v", err)
 	}
-	if rowsAffected == 0 {
-		return fmt.Errorf("элемент инвентаря с ID %v не найден", idInt)
+
+	if err := tx.Commit(); err != nil {
+		return plan, fmt.Errorf("не удалось завершить удаление: %v", err)
 	}
+	log.Printf("[DeleteInventoryItem] Ingredient %d: %s, removed from %d recipes", idInt, plan.Action, len(plan.UsedIn))
+	return plan, nil
+}
+
+// planInventoryDeletion блокирует строку ингредиента и собирает рецепты, в которых он
+// используется. Возвращает также текущую версию строки.
+func planInventoryDeletion(q queryer, id int) (models.InventoryDeletePlan, int, error) {
+	plan := models.InventoryDeletePlan{InventoryID: id, UsedIn: []models.InventoryUsage{}}
+
+	var version int
+	var archived bool
+	err := q.QueryRow(`SELECT name, version, archived_at IS NOT NULL,
+							EXISTS(SELECT 1 FROM inventory_transactions
+								   WHERE inventory_id = $1 AND reference_type IS DISTINCT FROM 'opening')
+					   FROM inventory WHERE id = $1 FOR UPDATE`, id).Scan(&plan.Name, &version, &archived, &plan.HasHistory)
+	if err == sql.ErrNoRows {
+		return plan, 0, fmt.Errorf("%w: элемент инвентаря с ID %v", ErrNotFound, id)
+	} else if err != nil {
+		return plan, 0, fmt.Errorf("ошибка при проверке ингредиента: %v", err)
+	}
+	if archived {
+		return plan, version, fmt.Errorf("%w: ингредиент %d уже в архиве", ErrConflict, id)
+	}
+
+	plan.Action = "delete"
+	if plan.HasHistory {
+		plan.Action = "archive"
+	}
+
+	rows, err := q.Query(`
+		SELECT mi.id, mi.name, '', 0, ''
+		FROM menu_item_ingredients mii JOIN menu_items mi ON mi.id = mii.menu_item_id
+		WHERE mii.ingredient_id = $1
+		UNION ALL
+		SELECT mi.id, mi.name, v.size::TEXT, 0, ''
+		FROM menu_item_variant_ingredients vi
+		JOIN menu_item_variants v ON v.id = vi.variant_id
+		JOIN menu_items mi ON mi.id = v.menu_item_id
+		WHERE vi.ingredient_id = $1
+		UNION ALL
+		SELECT DISTINCT 0, '', '', m.id, m.code
+		FROM modifier_ingredients mdi JOIN modifiers m ON m.id = mdi.modifier_id
+		WHERE mdi.ingredient_id = $1 OR mdi.replaces_ingredient_id = $1
+		ORDER BY 1, 3, 4`, id)
+	if err != nil {
+		return plan, 0, fmt.Errorf("ошибка при поиске рецептов с ингредиентом: %v", err)
+	}
+	defer rows.Close()
 
-	return nil
+	for rows.Next() {
+		var u models.InventoryUsage
+		if err := rows.Scan(&u.MenuItemID, &u.MenuItemName, &u.VariantSize, &u.ModifierID, &u.ModifierCode); err != nil {
+			return plan, 0, fmt.Errorf("ошибка при сканировании рецепта: %v", err)
+		}
+		plan.UsedIn = append(plan.UsedIn, u)
+	}
+	if err := rows.Err(); err != nil {
+		return plan, 0, fmt.Errorf("ошибка при итерации по рецептам: %v", err)
+	}
+
+	if plan.BlockedBy, err = getInventoryReferences(q, id); err != nil {
+		return plan, 0, err
+	}
+
+	return plan, version, nil
+}
+
+// getInventoryReferences возвращает документы поставщиков, которые ссылаются
+// на ингредиент. Каталог поставщика удалился бы каскадом, но молча терять
+// его не стоит — он тоже мешает удалению.
+func getInventoryReferences(q queryer, id int) ([]models.InventoryReference, error) {
+	rows, err := q.Query(`
+		SELECT DISTINCT 'purchase_order', purchase_order_id FROM purchase_order_lines WHERE inventory_id = $1
+		UNION
+		SELECT DISTINCT 'goods_receipt', receipt_id FROM goods_receipt_lines WHERE inventory_id = $1
+		UNION
+		SELECT DISTINCT 'supplier', supplier_id FROM supplier_items WHERE inventory_id = $1
+		ORDER BY 1, 2`, id)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при поиске документов с ингредиентом: %v", err)
+	}
+	defer rows.Close()
+
+	var refs []models.InventoryReference
+	for rows.Next() {
+		var ref models.InventoryReference
+		if err := rows.Scan(&ref.Type, &ref.ID); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании документа: %v", err)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
 }
@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"frappuccino/models"
+)
+
+// Типы документов-оснований в inventory_transactions.reference_type
+const (
+	ReferenceOrder        = "order"
+	ReferenceGoodsReceipt = "goods_receipt"
+	ReferenceWaste        = "waste"
+	ReferenceStocktake    = "stocktake"
+	ReferenceOpening      = "opening"
+	ReferenceAdjustment   = "adjustment"
+)
+
+// recordInventoryTransaction пишет движение в журнал. Вызывается в той же транзакции,
+// что и изменение inventory.quantity, чтобы журнал и остаток не расходились.
+// Если TransactionDate не задан, движение датируется текущим моментом.
+func recordInventoryTransaction(q queryer, tx models.InventoryTransaction) error {
+	var referenceType sql.NullString
+	if tx.ReferenceType != "" {
+		referenceType = sql.NullString{String: tx.ReferenceType, Valid: true}
+	}
+
+	transactionDate := sql.NullTime{Time: tx.TransactionDate, Valid: !tx.TransactionDate.IsZero()}
+
+	_, err := q.Exec(`INSERT INTO inventory_transactions
+						(inventory_id, change_amount, reason, unit_cost, reference_type, reference_id, transaction_date)
+					  VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7, NOW()))`,
+		tx.InventoryID, tx.ChangeAmount, tx.Reason, tx.UnitCost, referenceType, tx.ReferenceID, transactionDate)
+	if err != nil {
+		return fmt.Errorf("не удалось записать движение ингредиента #%d: %v", tx.InventoryID, err)
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+	"time"
+)
+
+// createLot заводит партию при поступлении.
+func createLot(q queryer, lot models.InventoryLot) error {
+	_, err := q.Exec(`INSERT INTO inventory_lots
+						(inventory_id, quantity, initial_quantity, unit_cost, received_at, expires_at, goods_receipt_line_id)
+					  VALUES ($1, $2, $2, $3, $4, $5, $6)`,
+		lot.InventoryID, lot.Quantity, lot.UnitCost, lot.ReceivedAt, lot.ExpiresAt, lot.GoodsReceiptLineID)
+	if err != nil {
+		return fmt.Errorf("не удалось создать партию ингредиента #%d: %v", lot.InventoryID, err)
+	}
+	return nil
+}
+
+type openLot struct {
+	id       int
+	quantity models.Quantity
+}
+
+// consumeLots списывает amount с открытых непросроченных партий ингредиента,
+// начиная с самой старой. Если партий не хватает, остаток списывается с количества
+// без партии — его уже уменьшил UPDATE inventory в вызывающем коде.
+func consumeLots(q queryer, inventoryID int, amount models.Quantity) error {
+	rows, err := q.Query(`SELECT id, quantity FROM inventory_lots
+						  WHERE inventory_id = $1 AND quantity > 0 AND (expires_at IS NULL OR expires_at > NOW())
+						  ORDER BY received_at, id
+						  FOR UPDATE`, inventoryID)
+	if err != nil {
+		return fmt.Errorf("ошибка при получении партий ингредиента #%d: %v", inventoryID, err)
+	}
+
+	var lots []openLot
+	for rows.Next() {
+		var lot openLot
+		if err := rows.Scan(&lot.id, &lot.quantity); err != nil {
+			rows.Close()
+			return fmt.Errorf("ошибка при сканировании партии: %v", err)
+		}
+		lots = append(lots, lot)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("ошибка при итерации по партиям: %v", err)
+	}
+
+	for _, lot := range lots {
+		if amount <= 0 {
+			break
+		}
+		take := lot.quantity
+		if take > amount {
+			take = amount
+		}
+		_, err := q.Exec(`UPDATE inventory_lots SET quantity = quantity - $1::NUMERIC WHERE id = $2`, take, lot.id)
+		if err != nil {
+			return fmt.Errorf("не удалось списать партию #%d: %v", lot.id, err)
+		}
+		amount -= take
+	}
+	return nil
+}
+
+// writeOffExpiredLots списывает остатки просроченных партий ингредиента как потери
+// с причиной expired. Возвращает число списанных партий.
+// Порядок блокировок везде один: сначала строка inventory, потом её партии —
+// так же, как в deductIngredients, иначе параллельные транзакции могут взаимно заблокироваться.
+func writeOffExpiredLots(q queryer, inventoryID int) (int, error) {
+	// Остаток ингредиента могли уменьшить вручную — списываем не больше, чем есть
+	var stock models.Quantity
+	err := q.QueryRow(`SELECT quantity FROM inventory WHERE id = $1 FOR UPDATE`, inventoryID).Scan(&stock)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("ошибка при получении остатка ингредиента #%d: %v", inventoryID, err)
+	}
+
+	rows, err := q.Query(`SELECT id, quantity, unit_cost
+						  FROM inventory_lots
+						  WHERE inventory_id = $1 AND quantity > 0 AND expires_at <= NOW()
+						  ORDER BY id
+						  FOR UPDATE`, inventoryID)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при поиске просроченных партий: %v", err)
+	}
+
+	var expired []models.InventoryLot
+	for rows.Next() {
+		lot := models.InventoryLot{InventoryID: inventoryID}
+		var unitCost sql.NullFloat64
+		if err := rows.Scan(&lot.ID, &lot.Quantity, &unitCost); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("ошибка при сканировании партии: %v", err)
+		}
+		if unitCost.Valid {
+			lot.UnitCost = &unitCost.Float64
+		}
+		expired = append(expired, lot)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("ошибка при итерации по партиям: %v", err)
+	}
+
+	for _, lot := range expired {
+		written := lot.Quantity
+		if written > stock {
+			written = stock
+		}
+
+		if written > 0 {
+			_, err = q.Exec(`UPDATE inventory SET quantity = quantity - $1::NUMERIC, version = version + 1, last_updated = NOW()
+							 WHERE id = $2`, written, inventoryID)
+			if err != nil {
+				return 0, fmt.Errorf("не удалось списать просроченную партию #%d: %v", lot.ID, err)
+			}
+			stock -= written
+		}
+
+		_, err = q.Exec(`UPDATE inventory_lots SET quantity = 0, written_off_at = NOW() WHERE id = $1`, lot.ID)
+		if err != nil {
+			return 0, fmt.Errorf("не удалось закрыть партию #%d: %v", lot.ID, err)
+		}
+
+		if written <= 0 {
+			continue
+		}
+		lotID := lot.ID
+		_, err = recordWaste(q, models.WasteEntry{
+			InventoryID: inventoryID,
+			Quantity:    written,
+			Reason:      models.WasteExpired,
+			UnitCost:    lot.UnitCost,
+			LotID:       &lotID,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(expired), nil
+}
+
+// WriteOffExpiredLots списывает все просроченные партии. Каждый ингредиент
+// списывается в своей транзакции, чтобы фоновая задача не держала блокировки
+// нескольких строк inventory одновременно с заказами.
+func WriteOffExpiredLots() (int, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return 0, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	rows, err := dbConn.Query(`SELECT DISTINCT inventory_id FROM inventory_lots
+							   WHERE quantity > 0 AND expires_at <= NOW()
+							   ORDER BY inventory_id`)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при поиске просроченных партий: %v", err)
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("ошибка при сканировании ингредиента: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("ошибка при итерации по партиям: %v", err)
+	}
+
+	total := 0
+	for _, id := range ids {
+		n, err := writeOffExpiredLotsTx(dbConn, id)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+func writeOffExpiredLotsTx(dbConn *sql.DB, inventoryID int) (int, error) {
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("не удалось начать транзакцию: %v", err)
+	}
+	defer tx.Rollback()
+
+	n, err := writeOffExpiredLots(tx, inventoryID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("не удалось списать просроченные партии ингредиента #%d: %v", inventoryID, err)
+	}
+	return n, nil
+}
+
+// GetExpiringLots возвращает открытые партии, срок годности которых истекает
+// в ближайшие within (включая уже просроченные, но ещё не списанные).
+func GetExpiringLots(within time.Duration) ([]models.InventoryLot, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	rows, err := dbConn.Query(`
+		SELECT l.id, l.inventory_id, i.name, COALESCE(i.unit::TEXT, ''), l.quantity, l.initial_quantity,
+			l.unit_cost, l.received_at, l.expires_at, l.expires_at <= NOW(), l.goods_receipt_line_id
+		FROM inventory_lots l
+		JOIN inventory i ON i.id = l.inventory_id
+		WHERE l.quantity > 0 AND l.expires_at <= NOW() + $1::BIGINT * INTERVAL '1 second'
+		ORDER BY l.expires_at, l.id`, int64(within/time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении партий: %v", err)
+	}
+	defer rows.Close()
+
+	lots := []models.InventoryLot{}
+	for rows.Next() {
+		var lot models.InventoryLot
+		var unitCost sql.NullFloat64
+		err := rows.Scan(&lot.ID, &lot.InventoryID, &lot.Name, &lot.Unit, &lot.Quantity, &lot.InitialQuantity,
+			&unitCost, &lot.ReceivedAt, &lot.ExpiresAt, &lot.Expired, &lot.GoodsReceiptLineID)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании партии: %v", err)
+		}
+		if unitCost.Valid {
+			lot.UnitCost = &unitCost.Float64
+		}
+		lots = append(lots, lot)
+	}
+
+	return lots, rows.Err()
+}
@@ -0,0 +1,349 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"strconv"
+)
+
+// tierWindowDays — за сколько последних дней суммируются траты для уровня участника.
+const tierWindowDays = 365
+
+func CreateLoyaltyRule(rule models.LoyaltyRule) (int, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return 0, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	var id int
+	err = dbConn.QueryRow(`INSERT INTO loyalty_rules (name, category, points_per_unit, active)
+						   VALUES ($1, NULLIF($2, ''), $3, $4) RETURNING id`,
+		rule.Name, rule.Category, rule.PointsPerUnit, rule.Active).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("не удалось создать правило начисления: %v", err)
+	}
+
+	return id, nil
+}
+
+func GetLoyaltyRules() ([]models.LoyaltyRule, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	return getLoyaltyRules(dbConn, false)
+}
+
+func getLoyaltyRules(q queryer, activeOnly bool) ([]models.LoyaltyRule, error) {
+	rows, err := q.Query(`SELECT id, name, COALESCE(category, ''), points_per_unit, active
+						  FROM loyalty_rules WHERE active OR NOT $1 ORDER BY id`, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении правил начисления: %v", err)
+	}
+	defer rows.Close()
+
+	var rules []models.LoyaltyRule
+	for rows.Next() {
+		var rule models.LoyaltyRule
+		if err := rows.Scan(&rule.ID, &rule.Name, &rule.Category, &rule.PointsPerUnit, &rule.Active); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании правила начисления: %v", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
+
+func CreateLoyaltyTier(tier models.LoyaltyTier) (int, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return 0, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	var id int
+	err = dbConn.QueryRow(`INSERT INTO loyalty_tiers (name, min_spend, points_multiplier)
+						   VALUES ($1, $2, $3) RETURNING id`,
+		tier.Name, tier.MinSpend, tier.PointsMultiplier).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("не удалось создать уровень: %v", err)
+	}
+
+	return id, nil
+}
+
+func GetLoyaltyTiers() ([]models.LoyaltyTier, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	return getLoyaltyTiers(dbConn)
+}
+
+// getLoyaltyTiers возвращает уровни по возрастанию порога, как того ждёт utils.TierFor.
+func getLoyaltyTiers(q queryer) ([]models.LoyaltyTier, error) {
+	rows, err := q.Query(`SELECT id, name, min_spend, points_multiplier FROM loyalty_tiers ORDER BY min_spend`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении уровней: %v", err)
+	}
+	defer rows.Close()
+
+	var tiers []models.LoyaltyTier
+	for rows.Next() {
+		var tier models.LoyaltyTier
+		if err := rows.Scan(&tier.ID, &tier.Name, &tier.MinSpend, &tier.PointsMultiplier); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании уровня: %v", err)
+		}
+		tiers = append(tiers, tier)
+	}
+
+	return tiers, rows.Err()
+}
+
+// rollingSpend — траты покупателя после скидок (без налогов и чаевых)
+// по выполненным заказам за последние tierWindowDays дней.
+func rollingSpend(q queryer, customerID int) (float64, error) {
+	var spend float64
+	err := q.QueryRow(`SELECT COALESCE(SUM(subtotal - discount_total), 0) FROM orders
+					   WHERE customer_id = $1 AND status = 'completed'
+					     AND order_date >= NOW() - make_interval(days => $2)`,
+		customerID, tierWindowDays).Scan(&spend)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при подсчёте трат покупателя: %v", err)
+	}
+	return spend, nil
+}
+
+// pointsBalance — баланс баллов покупателя без списания по заказу excludeOrderID,
+// чтобы пересчёт заказа не учитывал собственное списание дважды.
+func pointsBalance(q queryer, customerID, excludeOrderID int) (int, error) {
+	var balance int
+	err := q.QueryRow(`SELECT COALESCE(SUM(points), 0) FROM loyalty_ledger
+					   WHERE customer_id = $1 AND NOT (reason = 'redeem' AND order_id IS NOT DISTINCT FROM $2)`,
+		customerID, excludeOrderID).Scan(&balance)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при подсчёте баланса баллов: %v", err)
+	}
+	return balance, nil
+}
+
+// CheckPointsBalance проверяет, что у покупателя хватает баллов для нового заказа.
+func CheckPointsBalance(customerID, points int) error {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	balance, err := pointsBalance(dbConn, customerID, 0)
+	if err != nil {
+		return err
+	}
+	if balance < points {
+		return fmt.Errorf("на счёте %d баллов, запрошено %d", balance, points)
+	}
+	return nil
+}
+
+// redeemOrderPoints списывает баллы по открытому заказу: не больше запрошенного,
+// не больше баланса покупателя и не больше стоимости заказа. Запись о списании
+// в журнале пересоздаётся при каждом пересчёте, как и скидки заказа.
+// Строка покупателя блокируется, чтобы параллельные заказы не потратили одни и те же баллы.
+func redeemOrderPoints(q queryer, b *utils.PriceBreakdown, orderID, customerID, requested int) (int, error) {
+	if _, err := q.Exec(`DELETE FROM loyalty_ledger WHERE order_id = $1 AND reason = 'redeem'`, orderID); err != nil {
+		return 0, fmt.Errorf("не удалось очистить списание баллов: %v", err)
+	}
+	if requested <= 0 || customerID == 0 {
+		return 0, nil
+	}
+
+	if _, err := q.Exec(`SELECT 1 FROM customers WHERE id = $1 FOR UPDATE`, customerID); err != nil {
+		return 0, fmt.Errorf("не удалось заблокировать покупателя: %v", err)
+	}
+	balance, err := pointsBalance(q, customerID, orderID)
+	if err != nil {
+		return 0, err
+	}
+	if requested > balance {
+		requested = balance
+	}
+
+	used := b.RedeemPoints(requested)
+	if used == 0 {
+		return 0, nil
+	}
+
+	_, err = q.Exec(`INSERT INTO loyalty_ledger (customer_id, order_id, points, reason, note)
+					 VALUES ($1, $2, $3, 'redeem', $4)`,
+		customerID, orderID, -used, fmt.Sprintf("Order #%d", orderID))
+	if err != nil {
+		return 0, fmt.Errorf("не удалось записать списание баллов: %v", err)
+	}
+	return used, nil
+}
+
+// earnOrderPoints начисляет баллы за выполненный заказ по активным правилам
+// с множителем текущего уровня покупателя. Повторное начисление по тому же
+// заказу не происходит благодаря уникальному индексу.
+func earnOrderPoints(q queryer, orderID int) error {
+	var customerID sql.NullInt64
+	var subtotal, discount float64
+	err := q.QueryRow(`SELECT customer_id, subtotal, discount_total FROM orders WHERE id = $1`, orderID).
+		Scan(&customerID, &subtotal, &discount)
+	if err != nil {
+		return fmt.Errorf("не удалось загрузить заказ #%d для начисления баллов: %v", orderID, err)
+	}
+	if !customerID.Valid {
+		return nil
+	}
+
+	lines, err := getPricingLines(q, orderID)
+	if err != nil {
+		return err
+	}
+	rules, err := getLoyaltyRules(q, true)
+	if err != nil {
+		return err
+	}
+	tiers, err := getLoyaltyTiers(q)
+	if err != nil {
+		return err
+	}
+	spend, err := rollingSpend(q, int(customerID.Int64))
+	if err != nil {
+		return err
+	}
+
+	multiplier := 1.0
+	if tier, _ := utils.TierFor(tiers, spend); tier != nil {
+		multiplier = tier.PointsMultiplier
+	}
+
+	points := utils.EarnPoints(lines, utils.ToCents(subtotal)-utils.ToCents(discount), rules, multiplier)
+	if points <= 0 {
+		return nil
+	}
+
+	_, err = q.Exec(`INSERT INTO loyalty_ledger (customer_id, order_id, points, reason, note)
+					 VALUES ($1, $2, $3, 'earn', $4)
+					 ON CONFLICT (order_id) WHERE reason = 'earn' DO NOTHING`,
+		customerID.Int64, orderID, points, fmt.Sprintf("Order #%d", orderID))
+	if err != nil {
+		return fmt.Errorf("не удалось начислить баллы: %v", err)
+	}
+	return nil
+}
+
+// reverseOrderPoints отменяет всё, что заказ сделал с баллами: возвращает списанные
+// и забирает начисленные. Записи журнала не удаляются, добавляется одна компенсирующая.
+func reverseOrderPoints(q queryer, orderID int) error {
+	_, err := q.Exec(`INSERT INTO loyalty_ledger (customer_id, order_id, points, reason, note)
+					  SELECT customer_id, order_id, -SUM(points), 'reversal', 'Order #' || order_id || ' canceled'
+					  FROM loyalty_ledger WHERE order_id = $1
+					  GROUP BY customer_id, order_id
+					  HAVING SUM(points) <> 0`, orderID)
+	if err != nil {
+		return fmt.Errorf("не удалось вернуть баллы по заказу: %v", err)
+	}
+	return nil
+}
+
+func customerExists(q queryer, id int) error {
+	var exists bool
+	if err := q.QueryRow(`SELECT EXISTS(SELECT 1 FROM customers WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return fmt.Errorf("ошибка при проверке покупателя: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: покупатель с ID %d", ErrNotFound, id)
+	}
+	return nil
+}
+
+// GetCustomerLoyalty возвращает баланс баллов, траты за окно и уровень покупателя.
+func GetCustomerLoyalty(idStr string) (models.CustomerLoyalty, error) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return models.CustomerLoyalty{}, fmt.Errorf("неверный формат ID: %v", err)
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return models.CustomerLoyalty{}, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	if err := customerExists(dbConn, id); err != nil {
+		return models.CustomerLoyalty{}, err
+	}
+
+	result := models.CustomerLoyalty{CustomerID: id, WindowDays: tierWindowDays}
+	if result.Balance, err = pointsBalance(dbConn, id, 0); err != nil {
+		return models.CustomerLoyalty{}, err
+	}
+	result.PointsValue = utils.FromCents(int64(result.Balance) * utils.PointValueCents)
+
+	if result.RollingSpend, err = rollingSpend(dbConn, id); err != nil {
+		return models.CustomerLoyalty{}, err
+	}
+	tiers, err := getLoyaltyTiers(dbConn)
+	if err != nil {
+		return models.CustomerLoyalty{}, err
+	}
+	result.Tier, result.NextTier = utils.TierFor(tiers, result.RollingSpend)
+	if result.NextTier != nil {
+		result.SpendToNext = utils.FromCents(utils.ToCents(result.NextTier.MinSpend) - utils.ToCents(result.RollingSpend))
+	}
+
+	return result, nil
+}
+
+// GetCustomerLedger возвращает журнал баллов покупателя с остатком после каждой записи.
+func GetCustomerLedger(idStr string) (models.CustomerLedger, error) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return models.CustomerLedger{}, fmt.Errorf("неверный формат ID: %v", err)
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return models.CustomerLedger{}, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	if err := customerExists(dbConn, id); err != nil {
+		return models.CustomerLedger{}, err
+	}
+
+	rows, err := dbConn.Query(`SELECT id, order_id, points, reason, COALESCE(note, ''), created_at
+							   FROM loyalty_ledger WHERE customer_id = $1 ORDER BY created_at, id`, id)
+	if err != nil {
+		return models.CustomerLedger{}, fmt.Errorf("ошибка при получении журнала баллов: %v", err)
+	}
+	defer rows.Close()
+
+	ledger := models.CustomerLedger{CustomerID: id, Entries: []models.LoyaltyEntry{}}
+	for rows.Next() {
+		var e models.LoyaltyEntry
+		var orderID sql.NullInt64
+		if err := rows.Scan(&e.ID, &orderID, &e.Points, &e.Reason, &e.Note, &e.CreatedAt); err != nil {
+			return models.CustomerLedger{}, fmt.Errorf("ошибка при сканировании записи журнала: %v", err)
+		}
+		if orderID.Valid {
+			v := int(orderID.Int64)
+			e.OrderID = &v
+		}
+		ledger.Balance += e.Points
+		e.Balance = ledger.Balance
+		ledger.Entries = append(ledger.Entries, e)
+	}
+
+	return ledger, rows.Err()
+}
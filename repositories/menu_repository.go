@@ -31,7 +31,7 @@ func CreateMenuItem(db *sql.DB, item models.MenuItem) (int, error) {
 	var id int
 
 	err = db.QueryRow(query, item.Name, item.Description, item.Price, pq.Array(item.Category), pq.Array(item.Allergens),
-		customizationOptionsJSON, item.Size, metadataJSON).Scan(&id)
+		customizationOptionsJSON, nullableSize(item.Size), metadataJSON).Scan(&id)
 
 	if err != nil {
 		return 0, fmt.Errorf("could not insert menu item: %v", err)
@@ -40,7 +40,9 @@ func CreateMenuItem(db *sql.DB, item models.MenuItem) (int, error) {
 }
 
 // DELETE --------------------------------------------------------------------------------------
-func DeleteMenuItem(idstr string) error {
+// DeleteMenuItem архивирует блюдо: оно пропадает из меню и недоступно для новых заказов,
+// но остаётся в прошлых заказах и отчётах. Рецепт сохраняется для восстановления.
+func DeleteMenuItem(idstr string, expectedVersion int) error {
 	const logPrefix = "[DeleteMenuItem]"
 
 	idint, err := strconv.Atoi(idstr)
@@ -56,105 +58,171 @@ func DeleteMenuItem(idstr string) error {
 	}
 	defer dbConn.Close()
 
-	log.Printf("%s Connection to the database was successful", logPrefix)
-
-	if err := DeleteMenuItemDependencies(dbConn, idint); err != nil {
-		return err
-	}
-
-	log.Printf("%s Remove item from menu_items with ID= %d", logPrefix, idint)
-	query := `DELETE FROM menu_items WHERE id = $1`
-	result, err := dbConn.Exec(query, idint)
+	log.Printf("%s Archive menu item with ID= %d", logPrefix, idint)
+	query := `UPDATE menu_items SET archived_at = NOW(), version = version + 1
+			  WHERE id = $1 AND version = $2 AND archived_at IS NULL`
+	result, err := dbConn.Exec(query, idint, expectedVersion)
 	if err != nil {
-		log.Printf("%s Error while deleting from menu_items: %v", logPrefix, err)
-		return fmt.Errorf("failed to delete menu item: %v", err)
+		log.Printf("%s Error while archiving menu item: %v", logPrefix, err)
+		return fmt.Errorf("failed to archive menu item: %v", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		log.Printf("%s Error getting number of deleted rows: %v", logPrefix, err)
-		return fmt.Errorf("error getting number of deleted rows: %v", err)
+		return fmt.Errorf("error getting number of archived rows: %v", err)
 	}
 	if rowsAffected == 0 {
-		log.Printf("%s Menu item with ID %d not found", logPrefix, idint)
-		return fmt.Errorf("menu item with ID %v not found", idint)
+		var archived bool
+		err := dbConn.QueryRow(`SELECT archived_at IS NOT NULL FROM menu_items WHERE id = $1`, idint).Scan(&archived)
+		if err == nil && archived {
+			return fmt.Errorf("%w: menu item with ID %d is already archived", ErrConflict, idint)
+		}
+		return menuItemVersionError(dbConn, idint, expectedVersion)
 	}
 
-	log.Printf("%s Menu item with ID %d successfully removed", logPrefix, idint)
+	log.Printf("%s Menu item with ID %d successfully archived", logPrefix, idint)
 	return nil
 }
 
+// RestoreMenuItem возвращает архивное блюдо в меню, только если его версия равна
+// expectedVersion, и отдаёт новую версию.
+func RestoreMenuItem(idstr string, expectedVersion int) (int, error) {
+	idint, err := strconv.Atoi(idstr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ID format: %v", err)
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect to database: %v", err)
+	}
+	defer dbConn.Close()
+
+	var version int
+	err = dbConn.QueryRow(`UPDATE menu_items SET archived_at = NULL, version = version + 1
+						   WHERE id = $1 AND version = $2 AND archived_at IS NOT NULL RETURNING version`,
+		idint, expectedVersion).Scan(&version)
+	if err == sql.ErrNoRows {
+		var archived bool
+		err := dbConn.QueryRow(`SELECT archived_at IS NOT NULL FROM menu_items WHERE id = $1`, idint).Scan(&archived)
+		if err == nil && !archived {
+			return 0, fmt.Errorf("%w: menu item with ID %d is not archived", ErrConflict, idint)
+		}
+		return 0, menuItemVersionError(dbConn, idint, expectedVersion)
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to restore menu item: %v", err)
+	}
+
+	log.Printf("[RestoreMenuItem] Menu item with ID %d restored", idint)
+	return version, nil
+}
+
 // UPDATE ------------------------------------------------------------------
-func UpdateMenuItem(idStr string, item models.MenuItem) error {
+// UpdateMenuItem обновляет блюдо, только если его версия равна expectedVersion,
+// и возвращает новую версию.
+func UpdateMenuItem(idStr string, item models.MenuItem, expectedVersion int) (int, error) {
 	const logPrefix = "[UpdateMenuItem]"
 
 	idInt, err := strconv.Atoi(idStr)
 	if err != nil {
 		log.Printf("%s Invalid ID format: %v", logPrefix, err)
-		return fmt.Errorf("invalid ID format: %v", err)
+		return 0, fmt.Errorf("invalid ID format: %v", err)
 	}
 
 	dbConn, err := db.InitDB()
 	if err != nil {
 		log.Printf("%s Failed to connect to DB: %v", logPrefix, err)
-		return fmt.Errorf("failed to connect to the database: %v", err)
+		return 0, fmt.Errorf("failed to connect to the database: %v", err)
 	}
 	defer dbConn.Close()
 
 	if err := utils.ValidateIngredients(item.Ingredients); err != nil {
 		log.Printf("%s Ingredient validation failed: %v", logPrefix, err)
-		return fmt.Errorf("ingredient validation failed: %v", err)
+		return 0, fmt.Errorf("ingredient validation failed: %v", err)
 	}
 
 	customizationOptionsJSON, err := json.Marshal(item.CustomizationOptions)
 	if err != nil {
-		return fmt.Errorf("could not serialize customization_options: %v", err)
+		return 0, fmt.Errorf("could not serialize customization_options: %v", err)
 	}
 	metadataJSON, err := json.Marshal(item.Metadata)
 	if err != nil {
-		return fmt.Errorf("could not serialize metadata: %v", err)
+		return 0, fmt.Errorf("could not serialize metadata: %v", err)
 	}
 
+	// Версия, рецепт, размеры и модификаторы меняются вместе: иначе сбой посередине
+	// оставил бы блюдо без рецепта под новой версией
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
 	query := `UPDATE menu_items 
 		SET name=$1, description=$2, price=$3, category=$4, allergens=$5, 
-		    customization_options=$6, size=$7, metadata=$8 
-		WHERE id=$9`
-	result, err := dbConn.Exec(query,
+		    customization_options=$6, size=$7, metadata=$8, version=version+1
+		WHERE id=$9 AND version=$10
+		RETURNING version`
+	var version int
+	err = tx.QueryRow(query,
 		item.Name, item.Description, item.Price,
 		pq.Array(item.Category), pq.Array(item.Allergens),
-		customizationOptionsJSON, item.Size, metadataJSON, idInt,
-	)
-	if err != nil {
+		customizationOptionsJSON, nullableSize(item.Size), metadataJSON, idInt, expectedVersion,
+	).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, menuItemVersionError(tx, idInt, expectedVersion)
+	} else if err != nil {
 		log.Printf("%s Failed to update menu item: %v", logPrefix, err)
-		return fmt.Errorf("failed to update menu item: %v", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %v", err)
-	}
-	if rowsAffected == 0 {
-		return fmt.Errorf("menu item with ID %d not found", idInt)
+		return 0, fmt.Errorf("failed to update menu item: %v", err)
 	}
 
-	if err := DeleteMenuItemDependencies(dbConn, idInt); err != nil {
+	if err := DeleteMenuItemDependencies(tx, idInt); err != nil {
 		log.Printf("%s Failed to delete dependencies: %v", logPrefix, err)
-		return err
+		return 0, err
 	}
 
 	for _, ingredient := range item.Ingredients {
-		if err := AddIngredientToMenu(idInt, ingredient.IngredientID, ingredient.QuantityRequired); err != nil {
+		if err := addIngredientToMenu(tx, idInt, ingredient.IngredientID, ingredient.QuantityRequired); err != nil {
 			log.Printf("%s Failed to add ingredient ID %d: %v", logPrefix, ingredient.IngredientID, err)
-			return err
+			return 0, err
 		}
 	}
 
+	if err := SyncMenuItemVariants(tx, idInt, item.Variants); err != nil {
+		log.Printf("%s Failed to update variants: %v", logPrefix, err)
+		return 0, err
+	}
+
+	if err := SetMenuItemModifiers(tx, idInt, item.ModifierIDs); err != nil {
+		log.Printf("%s Failed to update modifiers: %v", logPrefix, err)
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("%s Failed to commit menu item update: %v", logPrefix, err)
+		return 0, fmt.Errorf("failed to update menu item: %v", err)
+	}
+
 	log.Printf("%s Menu item ID %d updated successfully", logPrefix, idInt)
-	return nil
+	return version, nil
+}
+
+// menuItemVersionError объясняет, почему условный UPDATE не затронул строку.
+func menuItemVersionError(q queryer, id, expectedVersion int) error {
+	var current int
+	err := q.QueryRow(`SELECT version FROM menu_items WHERE id = $1`, id).Scan(&current)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: menu item with ID %d", ErrNotFound, id)
+	} else if err != nil {
+		return fmt.Errorf("failed to check menu item version: %v", err)
+	}
+	return fmt.Errorf("%w: expected version %d, current %d", ErrStale, expectedVersion, current)
 }
 
 // GET -----------------------------------------------------------------------------------
-func GetMenuItems() ([]models.MenuItem, error) {
+// GetMenuItems возвращает меню; архивные блюда — только при includeArchived,
+// при availableOnly — только блюда, которые можно приготовить из текущих остатков.
+func GetMenuItems(includeArchived, availableOnly bool) ([]models.MenuItem, error) {
 	// Подключаемся к базе данных
 	dbConn, err := db.InitDB()
 	if err != nil {
@@ -163,7 +231,8 @@ func GetMenuItems() ([]models.MenuItem, error) {
 	defer dbConn.Close()
 
 	// Запрашиваем все данные из таблицы menu_items
-	rows, err := dbConn.Query("SELECT id, name, description, price, category, allergens, customization_options, size, metadata FROM menu_items")
+	rows, err := dbConn.Query(`SELECT id, name, description, price, category, allergens, customization_options, size, metadata, version, archived_at
+							   FROM menu_items WHERE $1 OR archived_at IS NULL ORDER BY id`, includeArchived)
 	if err != nil {
 		return nil, fmt.Errorf("не удалось получить элементы меню: %v", err)
 	}
@@ -180,7 +249,7 @@ func GetMenuItems() ([]models.MenuItem, error) {
 		var metadata sql.NullString             // Для обработки поля metadata
 		var size sql.NullString
 
-		err := rows.Scan(&item.ID, &item.Name, &item.Description, &item.Price, &category, &allergens, &customizationOptions, &size, &metadata)
+		err := rows.Scan(&item.ID, &item.Name, &item.Description, &item.Price, &category, &allergens, &customizationOptions, &size, &metadata, &item.Version, &item.ArchivedAt)
 		if err != nil {
 			return nil, fmt.Errorf("ошибка при сканировании строки: %v", err)
 		}
@@ -237,7 +306,32 @@ func GetMenuItems() ([]models.MenuItem, error) {
 		return nil, fmt.Errorf("ошибка при итерации по строкам: %v", err)
 	}
 
-	return items, nil
+	// Размеры всех блюд одним запросом
+	variants, err := getMenuItemVariants(dbConn, 0)
+	if err != nil {
+		return nil, err
+	}
+	modifierIDs, err := getMenuItemModifierIDs(dbConn, 0)
+	if err != nil {
+		return nil, err
+	}
+	availability, err := getMenuAvailability(dbConn, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	result := items[:0]
+	for i := range items {
+		items[i].Variants = variants[items[i].ID]
+		items[i].ModifierIDs = modifierIDs[items[i].ID]
+		applyAvailability(&items[i], availability[items[i].ID])
+		if availableOnly && !items[i].Available {
+			continue
+		}
+		result = append(result, items[i])
+	}
+
+	return result, nil
 }
 
 // GET BY ID ------------------------------------------------------------------------------
@@ -257,7 +351,7 @@ func GetMenuItemByID(idstr string) ([]models.MenuItem, error) {
 	defer dbConn.Close()
 
 	// Запрос для получения элемента меню по ID
-	query := `SELECT id, name, description, price, category, allergens, customization_options, size, metadata 
+	query := `SELECT id, name, description, price, category, allergens, customization_options, size, metadata, version, archived_at
 			  FROM menu_items WHERE id = $1`
 
 	var item models.MenuItem
@@ -278,11 +372,13 @@ func GetMenuItemByID(idstr string) ([]models.MenuItem, error) {
 		&customizationOptions,
 		&size,
 		&metadata,
+		&item.Version,
+		&item.ArchivedAt,
 	)
 
 	if err == sql.ErrNoRows {
 		// Если записи с таким ID нет
-		return nil, fmt.Errorf("элемент меню с таким ID не найден")
+		return nil, fmt.Errorf("%w: элемент меню с таким ID", ErrNotFound)
 	} else if err != nil {
 		return nil, fmt.Errorf("ошибка при запросе элемента меню: %v", err)
 	}
@@ -330,7 +426,30 @@ func GetMenuItemByID(idstr string) ([]models.MenuItem, error) {
 		item.Size = "" // Если size NULL, то можно установить значение по умолчанию
 	}
 
+	variants, err := getMenuItemVariants(dbConn, item.ID)
+	if err != nil {
+		return nil, err
+	}
+	item.Variants = variants[item.ID]
+
+	modifierIDs, err := getMenuItemModifierIDs(dbConn, item.ID)
+	if err != nil {
+		return nil, err
+	}
+	item.ModifierIDs = modifierIDs[item.ID]
+
+	availability, err := getMenuAvailability(dbConn, item.ID)
+	if err != nil {
+		return nil, err
+	}
+	applyAvailability(&item, availability[item.ID])
+
 	// Возвращаем элемент меню в виде слайса (т.к. мы ожидаем слайс в API)
 	items := []models.MenuItem{item}
 	return items, nil
 }
+
+// size в menu_items — ENUM, поэтому пустую строку пишем как NULL
+func nullableSize(size string) sql.NullString {
+	return sql.NullString{String: size, Valid: size != ""}
+}
@@ -0,0 +1,198 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+// CREATE ---------------------------------------------------------------------------
+func AddVariantToMenu(menuItemID int, variant models.MenuItemVariant) error {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the database: %v", err)
+	}
+	defer dbConn.Close()
+
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := upsertVariant(tx, menuItemID, variant); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SyncMenuItemVariants приводит варианты блюда к переданному списку:
+// существующие размеры обновляются, новые добавляются, пропавшие архивируются.
+// Архивный размер нельзя заказать, но прошлые заказы сохраняют его вместе с рецептом;
+// если размер передан снова, он возвращается в продажу. Вызывается внутри транзакции обновления блюда.
+func SyncMenuItemVariants(q queryer, menuItemID int, variants []models.MenuItemVariant) error {
+	const logPrefix = "[SyncMenuItemVariants]"
+
+	keep := make([]int64, 0, len(variants))
+	for _, variant := range variants {
+		id, err := upsertVariant(q, menuItemID, variant)
+		if err != nil {
+			log.Printf("%s Failed to save %s variant of menu item %d: %v", logPrefix, variant.Size, menuItemID, err)
+			return err
+		}
+		keep = append(keep, int64(id))
+	}
+
+	_, err := q.Exec(`UPDATE menu_item_variants SET archived_at = NOW()
+					  WHERE menu_item_id = $1 AND NOT (id = ANY($2)) AND archived_at IS NULL`,
+		menuItemID, pq.Array(keep))
+	if err != nil {
+		return fmt.Errorf("error archiving stale variants: %v", err)
+	}
+
+	return nil
+}
+
+func upsertVariant(tx queryer, menuItemID int, variant models.MenuItemVariant) (int, error) {
+	query := `INSERT INTO menu_item_variants (menu_item_id, size, price, recipe_multiplier)
+			  VALUES ($1, $2, $3, $4)
+			  ON CONFLICT (menu_item_id, size)
+			  DO UPDATE SET price = EXCLUDED.price, recipe_multiplier = EXCLUDED.recipe_multiplier, archived_at = NULL
+			  RETURNING id`
+
+	var id int
+	err := tx.QueryRow(query, menuItemID, variant.Size, variant.Price, variant.RecipeMultiplier).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("error saving variant %s: %v", variant.Size, err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM menu_item_variant_ingredients WHERE variant_id = $1`, id); err != nil {
+		return 0, fmt.Errorf("error clearing recipe of variant %s: %v", variant.Size, err)
+	}
+
+	for _, ingredient := range variant.Ingredients {
+		_, err := tx.Exec(`INSERT INTO menu_item_variant_ingredients (variant_id, ingredient_id, quantity_required)
+						   VALUES ($1, $2, $3)`, id, ingredient.IngredientID, ingredient.QuantityRequired)
+		if err != nil {
+			return 0, fmt.Errorf("error inserting ingredient into variant %s: %v", variant.Size, err)
+		}
+	}
+
+	return id, nil
+}
+
+// GET -----------------------------------------------------------------------------------
+
+// getMenuItemVariants загружает неархивные варианты всех блюд (menuItemID == 0)
+// или одного блюда и группирует их по menu_item_id.
+func getMenuItemVariants(q queryer, menuItemID int) (map[int][]models.MenuItemVariant, error) {
+	rows, err := q.Query(`
+		SELECT v.id, v.menu_item_id, v.size, v.price, v.recipe_multiplier
+		FROM menu_item_variants v
+		WHERE ($1 = 0 OR v.menu_item_id = $1) AND v.archived_at IS NULL
+		ORDER BY v.menu_item_id, v.price`, menuItemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get menu item variants: %v", err)
+	}
+	defer rows.Close()
+
+	variants := make(map[int][]models.MenuItemVariant)
+	index := make(map[int][2]int) // variant_id -> (menu_item_id, позиция в слайсе)
+
+	for rows.Next() {
+		var v models.MenuItemVariant
+		var itemID int
+		var multiplier sql.NullString
+		if err := rows.Scan(&v.ID, &itemID, &v.Size, &v.Price, &multiplier); err != nil {
+			return nil, fmt.Errorf("error scanning variant: %v", err)
+		}
+		if multiplier.Valid {
+			var m models.Quantity
+			if err := m.Scan(multiplier.String); err != nil {
+				return nil, fmt.Errorf("error scanning recipe_multiplier: %v", err)
+			}
+			v.RecipeMultiplier = &m
+		}
+		index[v.ID] = [2]int{itemID, len(variants[itemID])}
+		variants[itemID] = append(variants[itemID], v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating variants: %v", err)
+	}
+
+	ingRows, err := q.Query(`
+		SELECT vi.variant_id, vi.ingredient_id, vi.quantity_required
+		FROM menu_item_variant_ingredients vi
+		JOIN menu_item_variants v ON v.id = vi.variant_id
+		WHERE ($1 = 0 OR v.menu_item_id = $1) AND v.archived_at IS NULL
+		ORDER BY vi.id`, menuItemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get variant ingredients: %v", err)
+	}
+	defer ingRows.Close()
+
+	for ingRows.Next() {
+		var variantID int
+		var ing models.IngredientInfo
+		if err := ingRows.Scan(&variantID, &ing.IngredientID, &ing.QuantityRequired); err != nil {
+			return nil, fmt.Errorf("error scanning variant ingredient: %v", err)
+		}
+		pos, ok := index[variantID]
+		if !ok {
+			continue
+		}
+		list := variants[pos[0]]
+		list[pos[1]].Ingredients = append(list[pos[1]].Ingredients, ing)
+	}
+
+	return variants, ingRows.Err()
+}
+
+// GetOrderItemPrice возвращает цену порции: цену выбранного размера или базовую цену блюда.
+// Если у блюда есть варианты, размер обязателен. Архивное блюдо заказать нельзя (ErrConflict).
+func GetOrderItemPrice(menuItemID int, variantID *int) (float64, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return 0, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	var price float64
+	var hasVariants, archived bool
+	err = dbConn.QueryRow(`SELECT price, EXISTS(SELECT 1 FROM menu_item_variants WHERE menu_item_id = $1 AND archived_at IS NULL), archived_at IS NOT NULL
+						   FROM menu_items WHERE id = $1`, menuItemID).Scan(&price, &hasVariants, &archived)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("блюдо с ID %d не найдено", menuItemID)
+	} else if err != nil {
+		return 0, fmt.Errorf("ошибка при получении цены блюда: %v", err)
+	}
+	if archived {
+		return 0, fmt.Errorf("%w: блюдо %d снято с продажи", ErrConflict, menuItemID)
+	}
+
+	if variantID != nil {
+		var variantArchived bool
+		err = dbConn.QueryRow(`SELECT price, archived_at IS NOT NULL FROM menu_item_variants WHERE id = $1 AND menu_item_id = $2`,
+			*variantID, menuItemID).Scan(&price, &variantArchived)
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("размер с ID %d не найден у блюда %d", *variantID, menuItemID)
+		} else if err != nil {
+			return 0, fmt.Errorf("ошибка при получении цены размера: %v", err)
+		}
+		if variantArchived {
+			return 0, fmt.Errorf("%w: размер %d блюда %d снят с продажи", ErrConflict, *variantID, menuItemID)
+		}
+		return price, nil
+	}
+
+	if hasVariants {
+		return 0, fmt.Errorf("у блюда %d есть размеры, укажите variant_id", menuItemID)
+	}
+
+	return price, nil
+}
@@ -0,0 +1,245 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+
+	"github.com/lib/pq"
+)
+
+// CREATE ---------------------------------------------------------------------------
+func CreateModifier(modifier models.Modifier) (int, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect to the database: %v", err)
+	}
+	defer dbConn.Close()
+
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRow(`INSERT INTO modifiers (code, name, price_delta) VALUES ($1, $2, $3) RETURNING id`,
+		modifier.Code, modifier.Name, modifier.PriceDelta).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("could not insert modifier: %v", err)
+	}
+
+	for _, ing := range modifier.Ingredients {
+		_, err := tx.Exec(`INSERT INTO modifier_ingredients (modifier_id, ingredient_id, quantity, replaces_ingredient_id)
+						   VALUES ($1, $2, $3, $4)`, id, ing.IngredientID, ing.Quantity, ing.ReplacesIngredientID)
+		if err != nil {
+			return 0, fmt.Errorf("could not insert modifier ingredient: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("could not commit modifier: %v", err)
+	}
+	return id, nil
+}
+
+// GET -----------------------------------------------------------------------------------
+func GetModifiers() ([]models.Modifier, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the database: %v", err)
+	}
+	defer dbConn.Close()
+
+	rows, err := dbConn.Query(`SELECT id, code, name, price_delta FROM modifiers ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get modifiers: %v", err)
+	}
+	defer rows.Close()
+
+	var modifiers []models.Modifier
+	var ids []int64
+	for rows.Next() {
+		var m models.Modifier
+		if err := rows.Scan(&m.ID, &m.Code, &m.Name, &m.PriceDelta); err != nil {
+			return nil, fmt.Errorf("error scanning modifier: %v", err)
+		}
+		modifiers = append(modifiers, m)
+		ids = append(ids, int64(m.ID))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating modifiers: %v", err)
+	}
+
+	ingredients, err := getModifierIngredients(dbConn, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range modifiers {
+		for _, ing := range ingredients[modifiers[i].ID] {
+			modifiers[i].Ingredients = append(modifiers[i].Ingredients, ing.ModifierIngredient)
+		}
+	}
+
+	return modifiers, nil
+}
+
+// modifierIngredient — строка modifier_ingredients вместе с названием ингредиента для рецепта.
+type modifierIngredient struct {
+	models.ModifierIngredient
+	Name string
+}
+
+func getModifierIngredients(q queryer, modifierIDs []int64) (map[int][]modifierIngredient, error) {
+	rows, err := q.Query(`
+		SELECT mi.modifier_id, mi.ingredient_id, i.name, mi.quantity, mi.replaces_ingredient_id
+		FROM modifier_ingredients mi
+		JOIN inventory i ON i.id = mi.ingredient_id
+		WHERE mi.modifier_id = ANY($1)
+		ORDER BY mi.id`, pq.Array(modifierIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get modifier ingredients: %v", err)
+	}
+	defer rows.Close()
+
+	result := make(map[int][]modifierIngredient)
+	for rows.Next() {
+		var modifierID int
+		var ing modifierIngredient
+		var quantity sql.NullString
+		var replaces sql.NullInt64
+		if err := rows.Scan(&modifierID, &ing.IngredientID, &ing.Name, &quantity, &replaces); err != nil {
+			return nil, fmt.Errorf("error scanning modifier ingredient: %v", err)
+		}
+		if quantity.Valid {
+			var qty models.Quantity
+			if err := qty.Scan(quantity.String); err != nil {
+				return nil, fmt.Errorf("error scanning modifier quantity: %v", err)
+			}
+			ing.Quantity = &qty
+		}
+		if replaces.Valid {
+			id := int(replaces.Int64)
+			ing.ReplacesIngredientID = &id
+		}
+		result[modifierID] = append(result[modifierID], ing)
+	}
+
+	return result, rows.Err()
+}
+
+// SetMenuItemModifiers заменяет список модификаторов, доступных для блюда.
+func SetMenuItemModifiers(q queryer, menuItemID int, modifierIDs []int) error {
+	if _, err := q.Exec(`DELETE FROM menu_item_modifiers WHERE menu_item_id = $1`, menuItemID); err != nil {
+		return fmt.Errorf("error clearing menu item modifiers: %v", err)
+	}
+
+	for _, modifierID := range modifierIDs {
+		_, err := q.Exec(`INSERT INTO menu_item_modifiers (menu_item_id, modifier_id) VALUES ($1, $2)
+						  ON CONFLICT DO NOTHING`, menuItemID, modifierID)
+		if err != nil {
+			return fmt.Errorf("error adding modifier %d to menu item: %v", modifierID, err)
+		}
+	}
+	return nil
+}
+
+// getMenuItemModifierIDs возвращает доступные модификаторы всех блюд (menuItemID == 0) или одного.
+func getMenuItemModifierIDs(q queryer, menuItemID int) (map[int][]int, error) {
+	rows, err := q.Query(`SELECT menu_item_id, modifier_id FROM menu_item_modifiers
+						  WHERE $1 = 0 OR menu_item_id = $1 ORDER BY menu_item_id, modifier_id`, menuItemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get menu item modifiers: %v", err)
+	}
+	defer rows.Close()
+
+	result := make(map[int][]int)
+	for rows.Next() {
+		var itemID, modifierID int
+		if err := rows.Scan(&itemID, &modifierID); err != nil {
+			return nil, fmt.Errorf("error scanning menu item modifier: %v", err)
+		}
+		result[itemID] = append(result[itemID], modifierID)
+	}
+	return result, rows.Err()
+}
+
+// ValidateOrderItemModifiers проверяет, что модификаторы разрешены для блюда,
+// и возвращает их с зафиксированной доплатой. Количество по умолчанию — 1.
+func ValidateOrderItemModifiers(menuItemID int, selected []models.OrderItemModifier) ([]models.OrderItemModifier, error) {
+	if len(selected) == 0 {
+		return nil, nil
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	result := make([]models.OrderItemModifier, 0, len(selected))
+	seen := make(map[int]bool)
+	for _, m := range selected {
+		if m.Quantity == 0 {
+			m.Quantity = 1
+		}
+		if m.Quantity < 0 {
+			return nil, fmt.Errorf("количество модификатора %d должно быть больше 0", m.ModifierID)
+		}
+		if seen[m.ModifierID] {
+			return nil, fmt.Errorf("модификатор %d указан несколько раз", m.ModifierID)
+		}
+		seen[m.ModifierID] = true
+
+		var allowed bool
+		err := dbConn.QueryRow(`
+			SELECT m.price_delta, EXISTS(
+				SELECT 1 FROM menu_item_modifiers mim WHERE mim.menu_item_id = $2 AND mim.modifier_id = m.id)
+			FROM modifiers m WHERE m.id = $1`, m.ModifierID, menuItemID).Scan(&m.PriceDelta, &allowed)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("модификатор с ID %d не найден", m.ModifierID)
+		} else if err != nil {
+			return nil, fmt.Errorf("ошибка при проверке модификатора: %v", err)
+		}
+		if !allowed {
+			return nil, fmt.Errorf("модификатор %d недоступен для блюда %d", m.ModifierID, menuItemID)
+		}
+
+		result = append(result, m)
+	}
+
+	return result, nil
+}
+
+func insertOrderItemModifiers(q queryer, orderItemID int, modifiers []models.OrderItemModifier) error {
+	for _, m := range modifiers {
+		_, err := q.Exec(`INSERT INTO order_item_modifiers (order_item_id, modifier_id, quantity, price_delta)
+						  VALUES ($1, $2, $3, $4)`, orderItemID, m.ModifierID, m.Quantity, m.PriceDelta)
+		if err != nil {
+			return fmt.Errorf("не удалось сохранить модификатор %d: %v", m.ModifierID, err)
+		}
+	}
+	return nil
+}
+
+// getOrderItemModifiers загружает модификаторы позиций, сгруппированные по order_item_id.
+func getOrderItemModifiers(q queryer, orderItemIDs []int64) (map[int][]models.OrderItemModifier, error) {
+	rows, err := q.Query(`SELECT order_item_id, modifier_id, quantity, price_delta
+						  FROM order_item_modifiers WHERE order_item_id = ANY($1) ORDER BY id`, pq.Array(orderItemIDs))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при запросе модификаторов: %v", err)
+	}
+	defer rows.Close()
+
+	result := make(map[int][]models.OrderItemModifier)
+	for rows.Next() {
+		var orderItemID int
+		var m models.OrderItemModifier
+		if err := rows.Scan(&orderItemID, &m.ModifierID, &m.Quantity, &m.PriceDelta); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании модификатора: %v", err)
+		}
+		result[orderItemID] = append(result[orderItemID], m)
+	}
+	return result, rows.Err()
+}
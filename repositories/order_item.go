@@ -21,7 +21,7 @@ func GetOrderItemsByOrderID(orderIDStr string) ([]models.OrderItem, error) {
 	}
 	defer dbConn.Close()
 
-	query := `SELECT id, order_id, menu_item_id, quantity, price_at_order_time, customization FROM order_items WHERE order_id = $1`
+	query := `SELECT id, order_id, menu_item_id, variant_id, quantity, price_at_order_time, customization FROM order_items WHERE order_id = $1`
 
 	rows, err := dbConn.Query(query, orderID)
 	if err != nil {
@@ -34,11 +34,13 @@ func GetOrderItemsByOrderID(orderIDStr string) ([]models.OrderItem, error) {
 	for rows.Next() {
 		var item models.OrderItem
 		var customization sql.NullString
+		var variantID sql.NullInt64
 
 		err := rows.Scan(
 			&item.ID,
 			&item.OrderID,
 			&item.MenuItemID,
+			&variantID,
 			&item.Quantity,
 			&item.PriceAtOrderTime,
 			&customization,
@@ -47,6 +49,11 @@ func GetOrderItemsByOrderID(orderIDStr string) ([]models.OrderItem, error) {
 			return nil, fmt.Errorf("ошибка при сканировании строки: %v", err)
 		}
 
+		if variantID.Valid {
+			v := int(variantID.Int64)
+			item.VariantID = &v
+		}
+
 		if customization.Valid {
 			err = json.Unmarshal([]byte(customization.String), &item.Customization)
 			if err != nil {
@@ -59,6 +66,18 @@ func GetOrderItemsByOrderID(orderIDStr string) ([]models.OrderItem, error) {
 		items = append(items, item)
 	}
 
+	ids := make([]int64, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, int64(item.ID))
+	}
+	modifiers, err := getOrderItemModifiers(dbConn, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		items[i].Modifiers = modifiers[items[i].ID]
+	}
+
 	return items, nil
 }
 
@@ -74,15 +93,39 @@ func CreateOrderItem(item models.OrderItem) (int, error) {
 		return 0, fmt.Errorf("ошибка сериализации кастомизации: %v", err)
 	}
 
-	query := `INSERT INTO order_items (order_id, menu_item_id, quantity, price_at_order_time, customization)
-			  VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("не удалось начать транзакцию: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO order_items (order_id, menu_item_id, variant_id, quantity, price_at_order_time, customization)
+			  VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
 
 	var id int
-	err = dbConn.QueryRow(query, item.OrderID, item.MenuItemID, item.Quantity, item.PriceAtOrderTime, customJSON).Scan(&id)
+	err = tx.QueryRow(query, item.OrderID, item.MenuItemID, item.VariantID, item.Quantity, item.PriceAtOrderTime, customJSON).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("не удалось добавить позицию в заказ: %v", err)
 	}
 
+	if err := insertOrderItemModifiers(tx, id, item.Modifiers); err != nil {
+		return 0, err
+	}
+
+	// Позиция и списание ингредиентов сохраняются вместе или не сохраняются вовсе
+	item.ID = id
+	if err := deductIngredients(tx, item); err != nil {
+		return 0, err
+	}
+
+	if err := repriceOrder(tx, item.OrderID); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("не удалось сохранить позицию: %v", err)
+	}
+
 	return id, nil
 }
 
@@ -98,97 +141,111 @@ func DeleteOrderItem(idStr string) error {
 	}
 	defer dbConn.Close()
 
-	query := `DELETE FROM order_items WHERE id = $1`
-	result, err := dbConn.Exec(query, idInt)
+	tx, err := dbConn.Begin()
 	if err != nil {
+		return fmt.Errorf("не удалось начать транзакцию: %v", err)
+	}
+	defer tx.Rollback()
+
+	var orderID int
+	query := `DELETE FROM order_items WHERE id = $1 RETURNING order_id`
+	err = tx.QueryRow(query, idInt).Scan(&orderID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("позиция с ID %v не найдена", idInt)
+	} else if err != nil {
 		return fmt.Errorf("ошибка при удалении позиции: %v", err)
 	}
 
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("не удалось получить результат удаления: %v", err)
+	if err := repriceOrder(tx, orderID); err != nil {
+		return err
 	}
-	if rows == 0 {
-		return fmt.Errorf("позиция с ID %v не найдена", idInt)
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("не удалось удалить позицию: %v", err)
 	}
 
 	return nil
 }
 
-func HasEnoughIngredients(menuItemID int, quantity int) (bool, error) {
+func HasEnoughIngredients(item models.OrderItem) (bool, error) {
 	dbConn, err := db.InitDB()
 	if err != nil {
 		return false, fmt.Errorf("не удалось подключиться к БД: %v", err)
 	}
 	defer dbConn.Close()
 
-	query := `
-	SELECT 
-		i.name,
-		i.quantity AS stock_quantity,
-		mii.quantity_required * $2 AS required_quantity
-	FROM 
-		menu_item_ingredients mii
-	JOIN 
-		inventory i ON mii.ingredient_id = i.id
-	WHERE 
-		mii.menu_item_id = $1
-	`
-
-	rows, err := dbConn.Query(query, menuItemID, quantity)
+	lines, err := ResolveRecipe(dbConn, item)
 	if err != nil {
-		return false, fmt.Errorf("ошибка при проверке остатков: %v", err)
+		return false, err
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var name string
-		var stock, required int
-		if err := rows.Scan(&name, &stock, &required); err != nil {
-			return false, fmt.Errorf("ошибка при сканировании остатков: %v", err)
+	for _, line := range lines {
+		var stock models.Quantity
+		err := dbConn.QueryRow(`SELECT quantity FROM inventory WHERE id = $1`, line.IngredientID).Scan(&stock)
+		if err != nil {
+			return false, fmt.Errorf("ошибка при проверке остатков: %v", err)
 		}
 
-		if stock < required {
-			return false, fmt.Errorf("недостаточно ингредиента: %s (нужно %d, есть %d)", name, required, stock)
+		if stock < line.Quantity {
+			return false, fmt.Errorf("недостаточно ингредиента: %s (нужно %s, есть %s)", line.Name, line.Quantity, stock)
 		}
 	}
 
 	return true, nil
 }
 
-func DeductIngredients(menuItemID int, quantity int) error {
+func DeductIngredients(item models.OrderItem) error {
 	dbConn, err := db.InitDB()
 	if err != nil {
 		return fmt.Errorf("не удалось подключиться к БД: %v", err)
 	}
 	defer dbConn.Close()
 
-	query := `
-	SELECT 
-		ingredient_id,
-		quantity_required * $2 AS total_to_deduct
-	FROM 
-		menu_item_ingredients
-	WHERE 
-		menu_item_id = $1
-	`
-
-	rows, err := dbConn.Query(query, menuItemID, quantity)
+	return deductIngredients(dbConn, item)
+}
+
+// deductIngredients списывает рецепт позиции и пишет списание в журнал по текущей цене.
+// Остаток проверяется в самом UPDATE, поэтому при параллельных заказах количество не уходит в минус.
+// Партии расходуются от самой старой (FIFO).
+func deductIngredients(q queryer, item models.OrderItem) error {
+	lines, err := ResolveRecipe(q, item)
 	if err != nil {
-		return fmt.Errorf("ошибка при получении ингредиентов: %v", err)
+		return err
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var ingredientID, total int
-		if err := rows.Scan(&ingredientID, &total); err != nil {
-			return fmt.Errorf("ошибка при сканировании: %v", err)
+	for _, line := range lines {
+		// Просроченное не продаём: сначала списываем истёкшие партии
+		if _, err := writeOffExpiredLots(q, line.IngredientID); err != nil {
+			return err
 		}
 
-		update := `UPDATE inventory SET quantity = quantity - $1 WHERE id = $2`
-		_, err := dbConn.Exec(update, total, ingredientID)
-		if err != nil {
-			return fmt.Errorf("ошибка при списании ингредиента #%d: %v", ingredientID, err)
+		update := `UPDATE inventory SET quantity = quantity - $1, version = version + 1, last_updated = NOW()
+				   WHERE id = $2 AND quantity >= $1
+				   RETURNING price_per_unit`
+		var unitCost sql.NullFloat64
+		err := q.QueryRow(update, line.Quantity, line.IngredientID).Scan(&unitCost)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: недостаточно ингредиента %s (нужно %s)", ErrConflict, line.Name, line.Quantity)
+		} else if err != nil {
+			return fmt.Errorf("ошибка при списании ингредиента #%d: %v", line.IngredientID, err)
+		}
+
+		if err := consumeLots(q, line.IngredientID, line.Quantity); err != nil {
+			return err
+		}
+
+		movement := models.InventoryTransaction{
+			InventoryID:   line.IngredientID,
+			ChangeAmount:  -line.Quantity,
+			Reason:        fmt.Sprintf("Order #%d", item.OrderID),
+			ReferenceType: ReferenceOrder,
+			ReferenceID:   &item.OrderID,
+		}
+		if unitCost.Valid {
+			movement.UnitCost = &unitCost.Float64
+		}
+		if err := recordInventoryTransaction(q, movement); err != nil {
+			return err
 		}
 	}
 
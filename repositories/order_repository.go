@@ -17,7 +17,7 @@ func GetOrders() ([]models.Order, error) {
 	}
 	defer dbConn.Close()
 
-	query := `SELECT id, customer_id, status, special_instructions, total_amount, order_date FROM orders`
+	query := `SELECT id, customer_id, status, special_instructions, COALESCE(promo_code, ''), subtotal, discount_total, tax_total, tip_amount, tip_percent, total_amount, redeem_points, points_redeemed, order_date FROM orders`
 	rows, err := dbConn.Query(query)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка при выполнении запроса: %v", err)
@@ -35,7 +35,15 @@ func GetOrders() ([]models.Order, error) {
 			&order.CustomerID,
 			&order.Status,
 			&specialInstructions,
+			&order.PromoCode,
+			&order.Subtotal,
+			&order.DiscountTotal,
+			&order.TaxTotal,
+			&order.TipAmount,
+			&order.TipPercent,
 			&order.TotalAmount,
+			&order.RedeemPoints,
+			&order.PointsRedeemed,
 			&order.OrderDate,
 		)
 		if err != nil {
@@ -54,6 +62,23 @@ func GetOrders() ([]models.Order, error) {
 		orders = append(orders, order)
 	}
 
+	ids := make([]int64, 0, len(orders))
+	for _, order := range orders {
+		ids = append(ids, int64(order.ID))
+	}
+	discounts, err := getOrderDiscounts(dbConn, ids)
+	if err != nil {
+		return nil, err
+	}
+	taxLines, err := getOrderTaxLines(dbConn, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range orders {
+		orders[i].Discounts = discounts[orders[i].ID]
+		orders[i].TaxLines = taxLines[orders[i].ID]
+	}
+
 	return orders, nil
 }
 
@@ -69,11 +94,15 @@ func CreateOrder(order models.Order) (int, error) {
 		return 0, fmt.Errorf("ошибка сериализации special_instructions: %v", err)
 	}
 
-	query := `INSERT INTO orders (customer_id, status, special_instructions, total_amount)
-			  VALUES ($1, $2, $3, $4) RETURNING id`
+	// Сумма заказа не берётся от клиента: она пересчитывается при добавлении позиций
+	// Баллы списываются при пересчёте, когда в заказе появятся позиции
+	query := `INSERT INTO orders (customer_id, status, special_instructions, promo_code, tip_amount, tip_percent,
+				redeem_points, subtotal, discount_total, tax_total, total_amount)
+			  VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, 0, 0, 0, 0) RETURNING id`
 
 	var id int
-	err = dbConn.QueryRow(query, order.CustomerID, order.Status, specialInstructionsJSON, order.TotalAmount).Scan(&id)
+	err = dbConn.QueryRow(query, order.CustomerID, order.Status, specialInstructionsJSON, order.PromoCode,
+		order.TipAmount, order.TipPercent, order.RedeemPoints).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("не удалось создать заказ: %v", err)
 	}
@@ -94,7 +123,7 @@ func GetOrderById(idStr string) (models.Order, error) {
 	}
 	defer dbConn.Close()
 
-	query := `SELECT id, customer_id, status, special_instructions, total_amount, order_date FROM orders WHERE id = $1`
+	query := `SELECT id, customer_id, status, special_instructions, COALESCE(promo_code, ''), subtotal, discount_total, tax_total, tip_amount, tip_percent, total_amount, redeem_points, points_redeemed, order_date FROM orders WHERE id = $1`
 
 	var order models.Order
 	var specialInstructions sql.NullString
@@ -104,7 +133,15 @@ func GetOrderById(idStr string) (models.Order, error) {
 		&order.CustomerID,
 		&order.Status,
 		&specialInstructions,
+		&order.PromoCode,
+		&order.Subtotal,
+		&order.DiscountTotal,
+		&order.TaxTotal,
+		&order.TipAmount,
+		&order.TipPercent,
 		&order.TotalAmount,
+		&order.RedeemPoints,
+		&order.PointsRedeemed,
 		&order.OrderDate,
 	)
 	if err != nil {
@@ -123,6 +160,18 @@ func GetOrderById(idStr string) (models.Order, error) {
 		order.SpecialInstructions = nil
 	}
 
+	discounts, err := getOrderDiscounts(dbConn, []int64{int64(order.ID)})
+	if err != nil {
+		return models.Order{}, err
+	}
+	order.Discounts = discounts[order.ID]
+
+	taxLines, err := getOrderTaxLines(dbConn, []int64{int64(order.ID)})
+	if err != nil {
+		return models.Order{}, err
+	}
+	order.TaxLines = taxLines[order.ID]
+
 	return order, nil
 }
 
@@ -138,31 +187,57 @@ func UpdateOrderStatus(idStr string, status string) error {
 	}
 	defer dbConn.Close()
 
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return fmt.Errorf("не удалось начать транзакцию: %v", err)
+	}
+	defer tx.Rollback()
+
+	var previous string
+	err = tx.QueryRow(`SELECT status FROM orders WHERE id = $1 FOR UPDATE`, id).Scan(&previous)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("заказ с ID %v не найден", id)
+	} else if err != nil {
+		return fmt.Errorf("ошибка при получении заказа: %v", err)
+	}
+	// Баллы отменённого заказа уже возвращены, поэтому вернуть его в работу нельзя
+	if previous == "canceled" && status != previous {
+		return fmt.Errorf("%w: заказ #%d отменён", ErrConflict, id)
+	}
+
 	// 1. Обновляем статус в таблице orders
 	query := `UPDATE orders SET status = $1 WHERE id = $2`
-	result, err := dbConn.Exec(query, status, id)
-	if err != nil {
+	if _, err := tx.Exec(query, status, id); err != nil {
 		return fmt.Errorf("ошибка при обновлении заказа: %v", err)
 	}
 
-	affected, err := result.RowsAffected()
+	// 2. Записываем в историю
+	err = CreateOrderStatusHistory(tx, id, status)
 	if err != nil {
-		return fmt.Errorf("не удалось получить результат обновления: %v", err)
+		return fmt.Errorf("не удалось сохранить историю: %v", err)
 	}
-	if affected == 0 {
-		return fmt.Errorf("заказ с ID %v не найден", id)
+
+	// 3. Начисляем или возвращаем баллы лояльности
+	if status != previous {
+		switch status {
+		case "completed":
+			err = earnOrderPoints(tx, id)
+		case "canceled":
+			err = reverseOrderPoints(tx, id)
+		}
+		if err != nil {
+			return err
+		}
 	}
 
-	// 2. Записываем в историю
-	err = CreateOrderStatusHistory(dbConn, id, status)
-	if err != nil {
-		return fmt.Errorf("статус обновлён, но не удалось сохранить историю: %v", err)
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("не удалось сохранить статус: %v", err)
 	}
 
 	return nil
 }
 
-func CreateOrderStatusHistory(dbConn *sql.DB, orderID int, status string) error {
+func CreateOrderStatusHistory(dbConn queryer, orderID int, status string) error {
 	query := `INSERT INTO order_status_history (order_id, status) VALUES ($1, $2)`
 	_, err := dbConn.Exec(query, orderID, status)
 	if err != nil {
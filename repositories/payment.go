@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+	"strconv"
+)
+
+func CreatePayment(orderIDStr string, payment models.Payment) (int, error) {
+	orderID, err := strconv.Atoi(orderIDStr)
+	if err != nil {
+		return 0, fmt.Errorf("неверный формат order_id: %v", err)
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return 0, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	var exists bool
+	err = dbConn.QueryRow(`SELECT EXISTS(SELECT 1 FROM orders WHERE id = $1)`, orderID).Scan(&exists)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при проверке заказа: %v", err)
+	}
+	if !exists {
+		return 0, fmt.Errorf("заказ с ID %v не найден", orderID)
+	}
+
+	var id int
+	err = dbConn.QueryRow(`INSERT INTO payments (order_id, method, amount) VALUES ($1, $2, $3) RETURNING id`,
+		orderID, payment.Method, payment.Amount).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("не удалось сохранить оплату: %v", err)
+	}
+
+	return id, nil
+}
+
+func GetPayments(orderIDStr string) ([]models.Payment, error) {
+	orderID, err := strconv.Atoi(orderIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("неверный формат order_id: %v", err)
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	return getPayments(dbConn, orderID)
+}
+
+func getPayments(q queryer, orderID int) ([]models.Payment, error) {
+	rows, err := q.Query(`SELECT id, order_id, method, amount, paid_at FROM payments WHERE order_id = $1 ORDER BY paid_at, id`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при запросе оплат: %v", err)
+	}
+	defer rows.Close()
+
+	payments := []models.Payment{}
+	for rows.Next() {
+		var p models.Payment
+		if err := rows.Scan(&p.ID, &p.OrderID, &p.Method, &p.Amount, &p.PaidAt); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании оплаты: %v", err)
+		}
+		payments = append(payments, p)
+	}
+
+	return payments, rows.Err()
+}
@@ -0,0 +1,165 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// repriceOrder пересчитывает заказ по его позициям: подытог, скидки по акциям,
+// оплату баллами, налоги, чаевые и итог. Вызывается в той же транзакции, что и изменение заказа.
+func repriceOrder(q queryer, orderID int) error {
+	var customerID int
+	var status, promoCode string
+	var orderDate time.Time
+	var tipAmount float64
+	var tipPercent sql.NullFloat64
+	var redeemPoints, pointsRedeemed int
+	err := q.QueryRow(`SELECT COALESCE(customer_id, 0), status, COALESCE(promo_code, ''), order_date, tip_amount, tip_percent,
+							redeem_points, points_redeemed
+					   FROM orders WHERE id = $1`,
+		orderID).Scan(&customerID, &status, &promoCode, &orderDate, &tipAmount, &tipPercent, &redeemPoints, &pointsRedeemed)
+	if err != nil {
+		return fmt.Errorf("не удалось загрузить заказ #%d для расчёта: %v", orderID, err)
+	}
+
+	lines, err := getPricingLines(q, orderID)
+	if err != nil {
+		return err
+	}
+
+	promotions, err := getApplicablePromotions(q, orderID, customerID, promoCode)
+	if err != nil {
+		return err
+	}
+
+	rates, err := getTaxRates(q, true)
+	if err != nil {
+		return err
+	}
+
+	breakdown := utils.PriceOrder(lines, promotions, orderDate)
+
+	// У закрытого заказа журнал баллов уже не меняется: повторяем прежнее списание
+	if status == "completed" || status == "canceled" {
+		pointsRedeemed = breakdown.RedeemPoints(pointsRedeemed)
+	} else {
+		pointsRedeemed, err = redeemOrderPoints(q, &breakdown, orderID, customerID, redeemPoints)
+		if err != nil {
+			return err
+		}
+	}
+
+	breakdown.AddTaxes(lines, rates)
+	if tipPercent.Valid {
+		breakdown.AddTip(0, &tipPercent.Float64)
+	} else {
+		breakdown.AddTip(tipAmount, nil)
+	}
+
+	if _, err := q.Exec(`DELETE FROM order_discounts WHERE order_id = $1`, orderID); err != nil {
+		return fmt.Errorf("не удалось очистить скидки заказа: %v", err)
+	}
+	for _, d := range breakdown.Discounts {
+		_, err := q.Exec(`INSERT INTO order_discounts (order_id, promotion_id, points, description, amount)
+						  VALUES ($1, NULLIF($2, 0), NULLIF($3, 0), $4, $5)`,
+			orderID, d.PromotionID, d.Points, d.Description, d.Amount)
+		if err != nil {
+			return fmt.Errorf("не удалось сохранить скидку: %v", err)
+		}
+	}
+
+	if _, err := q.Exec(`DELETE FROM order_tax_lines WHERE order_id = $1`, orderID); err != nil {
+		return fmt.Errorf("не удалось очистить налоги заказа: %v", err)
+	}
+	for _, t := range breakdown.TaxLines {
+		_, err := q.Exec(`INSERT INTO order_tax_lines (order_id, tax_rate_id, name, rate, inclusive, taxable_amount, amount)
+						  VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			orderID, t.TaxRateID, t.Name, t.Rate, t.Inclusive, t.TaxableAmount, t.Amount)
+		if err != nil {
+			return fmt.Errorf("не удалось сохранить налог: %v", err)
+		}
+	}
+
+	_, err = q.Exec(`UPDATE orders SET subtotal = $1, discount_total = $2, tax_total = $3, tip_amount = $4, total_amount = $5,
+						points_redeemed = $6
+					 WHERE id = $7`,
+		utils.FromCents(breakdown.Subtotal), utils.FromCents(breakdown.Discount), utils.FromCents(breakdown.Tax),
+		utils.FromCents(breakdown.Tip), utils.FromCents(breakdown.Total), pointsRedeemed, orderID)
+	if err != nil {
+		return fmt.Errorf("не удалось сохранить сумму заказа: %v", err)
+	}
+
+	return nil
+}
+
+// getPricingLines загружает позиции заказа с категориями блюд.
+func getPricingLines(q queryer, orderID int) ([]models.PricingLine, error) {
+	rows, err := q.Query(`
+		SELECT oi.id, oi.price_at_order_time, oi.quantity, mi.category
+		FROM order_items oi
+		JOIN menu_items mi ON mi.id = oi.menu_item_id
+		WHERE oi.order_id = $1
+		ORDER BY oi.id`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении позиций заказа: %v", err)
+	}
+	defer rows.Close()
+
+	var lines []models.PricingLine
+	for rows.Next() {
+		var line models.PricingLine
+		if err := rows.Scan(&line.OrderItemID, &line.UnitPrice, &line.Quantity, pq.Array(&line.Categories)); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании позиции: %v", err)
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по позициям: %v", err)
+	}
+	return lines, nil
+}
+
+// SetOrderTip задаёт чаевые заказа (сумму или процент) и пересчитывает итог.
+func SetOrderTip(idStr string, amount float64, percent *float64) error {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return fmt.Errorf("неверный формат ID: %v", err)
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return fmt.Errorf("не удалось начать транзакцию: %v", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE orders SET tip_amount = $1, tip_percent = $2 WHERE id = $3`, amount, percent, id)
+	if err != nil {
+		return fmt.Errorf("ошибка при сохранении чаевых: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("не удалось получить результат обновления: %v", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("заказ с ID %v не найден", id)
+	}
+
+	if err := repriceOrder(tx, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,217 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// times_used и customer_used считают заказы со скидкой по акции, кроме отменённых
+// и кроме текущего заказа ($1), чтобы пересчёт заказа не съедал лимит повторно.
+const promotionSelect = `
+	SELECT p.id, p.name, COALESCE(p.code, ''), p.type, p.value, COALESCE(p.category, ''),
+		p.starts_at, p.ends_at,
+		COALESCE(to_char(p.happy_hour_start, 'HH24:MI'), ''), COALESCE(to_char(p.happy_hour_end, 'HH24:MI'), ''),
+		p.usage_limit, p.per_customer_limit, p.active,
+		(SELECT COUNT(DISTINCT od.order_id) FROM order_discounts od JOIN orders o ON o.id = od.order_id
+		 WHERE od.promotion_id = p.id AND o.status <> 'canceled' AND od.order_id <> $1),
+		(SELECT COUNT(DISTINCT od.order_id) FROM order_discounts od JOIN orders o ON o.id = od.order_id
+		 WHERE od.promotion_id = p.id AND o.status <> 'canceled' AND od.order_id <> $1 AND o.customer_id = $2)
+	FROM promotions p`
+
+func scanPromotion(rows *sql.Rows) (models.Promotion, int, error) {
+	var p models.Promotion
+	var startsAt, endsAt sql.NullTime
+	var usageLimit, perCustomerLimit sql.NullInt64
+	var customerUsed int
+
+	err := rows.Scan(&p.ID, &p.Name, &p.Code, &p.Type, &p.Value, &p.Category,
+		&startsAt, &endsAt, &p.HappyHourStart, &p.HappyHourEnd,
+		&usageLimit, &perCustomerLimit, &p.Active, &p.TimesUsed, &customerUsed)
+	if err != nil {
+		return p, 0, fmt.Errorf("ошибка при сканировании акции: %v", err)
+	}
+
+	if startsAt.Valid {
+		p.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		p.EndsAt = &endsAt.Time
+	}
+	if usageLimit.Valid {
+		v := int(usageLimit.Int64)
+		p.UsageLimit = &v
+	}
+	if perCustomerLimit.Valid {
+		v := int(perCustomerLimit.Int64)
+		p.PerCustomerLimit = &v
+	}
+
+	return p, customerUsed, nil
+}
+
+// withinLimits проверяет лимиты акции. Лимит на покупателя можно проверить
+// только у заказа с покупателем, поэтому гостевым заказам такие акции не даются.
+func withinLimits(p models.Promotion, customerID, customerUsed int) bool {
+	if p.UsageLimit != nil && p.TimesUsed >= *p.UsageLimit {
+		return false
+	}
+	if p.PerCustomerLimit != nil && customerID <= 0 {
+		return false
+	}
+	if p.PerCustomerLimit != nil && customerUsed >= *p.PerCustomerLimit {
+		return false
+	}
+	return true
+}
+
+func CreatePromotion(p models.Promotion) (int, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return 0, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	query := `INSERT INTO promotions (name, code, type, value, category, starts_at, ends_at,
+				happy_hour_start, happy_hour_end, usage_limit, per_customer_limit, active)
+			  VALUES ($1, NULLIF($2, ''), $3, $4, NULLIF($5, ''), $6, $7,
+				NULLIF($8, '')::TIME, NULLIF($9, '')::TIME, $10, $11, $12)
+			  RETURNING id`
+
+	var id int
+	err = dbConn.QueryRow(query, p.Name, p.Code, p.Type, p.Value, p.Category, p.StartsAt, p.EndsAt,
+		p.HappyHourStart, p.HappyHourEnd, p.UsageLimit, p.PerCustomerLimit, p.Active).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("не удалось создать акцию: %v", err)
+	}
+
+	return id, nil
+}
+
+func GetPromotions() ([]models.Promotion, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	rows, err := dbConn.Query(promotionSelect+` ORDER BY p.id`, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении акций: %v", err)
+	}
+	defer rows.Close()
+
+	var promotions []models.Promotion
+	for rows.Next() {
+		p, _, err := scanPromotion(rows)
+		if err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, p)
+	}
+
+	return promotions, rows.Err()
+}
+
+// CheckPromoCode проверяет, что промокод существует и может быть применён
+// к новому заказу покупателя прямо сейчас.
+func CheckPromoCode(code string, customerID int) error {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	rows, err := dbConn.Query(promotionSelect+` WHERE UPPER(p.code) = UPPER($3)`, 0, customerID, code)
+	if err != nil {
+		return fmt.Errorf("ошибка при проверке промокода: %v", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("ошибка при проверке промокода: %v", err)
+		}
+		return fmt.Errorf("промокод %s не найден", code)
+	}
+
+	p, customerUsed, err := scanPromotion(rows)
+	if err != nil {
+		return err
+	}
+	if !p.Active || (p.EndsAt != nil && !time.Now().Before(*p.EndsAt)) || (p.StartsAt != nil && time.Now().Before(*p.StartsAt)) {
+		return fmt.Errorf("промокод %s не действует", code)
+	}
+	if p.PerCustomerLimit != nil && customerID <= 0 {
+		return fmt.Errorf("промокод %s действует только для заказов с покупателем", code)
+	}
+	if !withinLimits(p, customerID, customerUsed) {
+		return fmt.Errorf("лимит использования промокода %s исчерпан", code)
+	}
+
+	return nil
+}
+
+// getApplicablePromotions возвращает автоматические акции и акцию по промокоду заказа,
+// у которых не исчерпаны лимиты. Время и категории проверяет utils.PriceOrder.
+// Акции с лимитами блокируются до конца транзакции пересчёта, иначе два параллельных
+// заказа увидят одно и то же число использований и оба получат последнюю скидку.
+// Использования считаются отдельным запросом уже после блокировки, чтобы видеть
+// скидки, записанные транзакцией, которая держала блокировку до нас.
+func getApplicablePromotions(q queryer, orderID, customerID int, promoCode string) ([]models.Promotion, error) {
+	_, err := q.Exec(`
+		SELECT id FROM promotions
+		WHERE active AND (code IS NULL OR ($1 <> '' AND UPPER(code) = UPPER($1)))
+		  AND (usage_limit IS NOT NULL OR per_customer_limit IS NOT NULL)
+		ORDER BY id
+		FOR UPDATE`, promoCode)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось заблокировать акции: %v", err)
+	}
+
+	rows, err := q.Query(promotionSelect+`
+		WHERE p.active AND (p.code IS NULL OR ($3 <> '' AND UPPER(p.code) = UPPER($3)))
+		ORDER BY p.id`, orderID, customerID, promoCode)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении акций: %v", err)
+	}
+	defer rows.Close()
+
+	var promotions []models.Promotion
+	for rows.Next() {
+		p, customerUsed, err := scanPromotion(rows)
+		if err != nil {
+			return nil, err
+		}
+		if withinLimits(p, customerID, customerUsed) {
+			promotions = append(promotions, p)
+		}
+	}
+
+	return promotions, rows.Err()
+}
+
+// getOrderDiscounts загружает скидки заказов, сгруппированные по order_id.
+func getOrderDiscounts(q queryer, orderIDs []int64) (map[int][]models.OrderDiscount, error) {
+	rows, err := q.Query(`SELECT order_id, COALESCE(promotion_id, 0), COALESCE(points, 0), description, amount
+						  FROM order_discounts WHERE order_id = ANY($1) ORDER BY id`, pq.Array(orderIDs))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при запросе скидок: %v", err)
+	}
+	defer rows.Close()
+
+	result := make(map[int][]models.OrderDiscount)
+	for rows.Next() {
+		var orderID int
+		var d models.OrderDiscount
+		if err := rows.Scan(&orderID, &d.PromotionID, &d.Points, &d.Description, &d.Amount); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании скидки: %v", err)
+		}
+		result[orderID] = append(result[orderID], d)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,324 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+	"math"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+// CreatePurchaseOrder создаёт черновик заказа. Строки заказываются упаковками
+// из каталога поставщика; размер и цена упаковки копируются в строку.
+func CreatePurchaseOrder(po models.PurchaseOrder) (int, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return 0, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("не удалось начать транзакцию: %v", err)
+	}
+	defer tx.Rollback()
+
+	var active bool
+	err = tx.QueryRow(`SELECT active FROM suppliers WHERE id = $1`, po.SupplierID).Scan(&active)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("%w: поставщик с ID %d", ErrNotFound, po.SupplierID)
+	} else if err != nil {
+		return 0, fmt.Errorf("ошибка при проверке поставщика: %v", err)
+	}
+	if !active {
+		return 0, fmt.Errorf("%w: поставщик %d неактивен", ErrConflict, po.SupplierID)
+	}
+
+	var id int
+	err = tx.QueryRow(`INSERT INTO purchase_orders (supplier_id, note) VALUES ($1, NULLIF($2, '')) RETURNING id`,
+		po.SupplierID, po.Note).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("не удалось создать заказ поставщику: %v", err)
+	}
+
+	seen := make(map[int]bool)
+	for _, line := range po.Lines {
+		if seen[line.InventoryID] {
+			return 0, fmt.Errorf("%w: ингредиент %d указан в заказе несколько раз", ErrConflict, line.InventoryID)
+		}
+		seen[line.InventoryID] = true
+
+		item, err := getSupplierItem(tx, po.SupplierID, line.InventoryID)
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(`INSERT INTO purchase_order_lines (purchase_order_id, inventory_id, packs, pack_size, pack_price)
+						  VALUES ($1, $2, $3, $4, $5)`, id, line.InventoryID, line.Packs, item.PackSize, item.PackPrice)
+		if err != nil {
+			return 0, fmt.Errorf("не удалось сохранить строку заказа: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("не удалось сохранить заказ поставщику: %v", err)
+	}
+	return id, nil
+}
+
+// SendPurchaseOrder отмечает черновик отправленным; ожидаемая дата — сейчас плюс срок поставки.
+func SendPurchaseOrder(idStr string) error {
+	return changePurchaseOrderStatus(idStr, models.PurchaseOrderSent,
+		[]string{models.PurchaseOrderDraft},
+		`sent_at = NOW(), expected_at = NOW() + (SELECT lead_time_days FROM suppliers s WHERE s.id = supplier_id) * INTERVAL '1 day'`)
+}
+
+// ClosePurchaseOrder закрывает заказ: черновик или отправленный, но так и не
+// доставленный — как отменённый, частично принятый — с недопоставкой.
+// Закрытый заказ больше не считается ожидаемой поставкой.
+func ClosePurchaseOrder(idStr string) error {
+	return changePurchaseOrderStatus(idStr, models.PurchaseOrderClosed,
+		[]string{models.PurchaseOrderDraft, models.PurchaseOrderSent, models.PurchaseOrderPartiallyReceived, models.PurchaseOrderReceived},
+		`closed_at = NOW()`)
+}
+
+func changePurchaseOrderStatus(idStr, status string, from []string, set string) error {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return fmt.Errorf("неверный формат ID: %v", err)
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	result, err := dbConn.Exec(`UPDATE purchase_orders SET status = $1, `+set+`
+								WHERE id = $2 AND status::TEXT = ANY($3)`, status, id, pq.Array(from))
+	if err != nil {
+		return fmt.Errorf("ошибка при смене статуса заказа поставщику: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("не удалось получить результат обновления: %v", err)
+	}
+	if affected == 0 {
+		return purchaseOrderStatusError(dbConn, id, status)
+	}
+	return nil
+}
+
+func purchaseOrderStatusError(q queryer, id int, target string) error {
+	var current string
+	err := q.QueryRow(`SELECT status FROM purchase_orders WHERE id = $1`, id).Scan(&current)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: заказ поставщику с ID %d", ErrNotFound, id)
+	} else if err != nil {
+		return fmt.Errorf("ошибка при проверке заказа поставщику: %v", err)
+	}
+	return fmt.Errorf("%w: заказ поставщику #%d в статусе %s, переход в %s невозможен", ErrConflict, id, current, target)
+}
+
+// ReceivePurchaseOrder принимает товар по отправленному заказу: проводит поступление
+// (остатки, средневзвешенная цена, журнал), увеличивает принятое количество строк
+// и переводит заказ в partially_received или received. Принять больше заказанного нельзя.
+func ReceivePurchaseOrder(idStr string, receiving models.PurchaseOrderReceiving) (models.GoodsReceipt, error) {
+	var receipt models.GoodsReceipt
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return receipt, fmt.Errorf("неверный формат ID: %v", err)
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return receipt, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return receipt, fmt.Errorf("не удалось начать транзакцию: %v", err)
+	}
+	defer tx.Rollback()
+
+	var status, supplier string
+	err = tx.QueryRow(`SELECT po.status, s.name FROM purchase_orders po JOIN suppliers s ON s.id = po.supplier_id
+					   WHERE po.id = $1 FOR UPDATE OF po`, id).Scan(&status, &supplier)
+	if err == sql.ErrNoRows {
+		return receipt, fmt.Errorf("%w: заказ поставщику с ID %d", ErrNotFound, id)
+	} else if err != nil {
+		return receipt, fmt.Errorf("ошибка при получении заказа поставщику: %v", err)
+	}
+	if status != models.PurchaseOrderSent && status != models.PurchaseOrderPartiallyReceived {
+		return receipt, fmt.Errorf("%w: заказ поставщику #%d в статусе %s, приёмка невозможна", ErrConflict, id, status)
+	}
+
+	lines, err := getPurchaseOrderLines(tx, []int64{int64(id)})
+	if err != nil {
+		return receipt, err
+	}
+	byID := make(map[int]*models.PurchaseOrderLine)
+	for i := range lines[id] {
+		byID[lines[id][i].ID] = &lines[id][i]
+	}
+
+	receipt = models.GoodsReceipt{
+		Supplier:        supplier,
+		Reference:       receiving.Reference,
+		Note:            receiving.Note,
+		PurchaseOrderID: &id,
+	}
+	for _, r := range receiving.Lines {
+		line, ok := byID[r.LineID]
+		if !ok {
+			return receipt, fmt.Errorf("%w: строка %d в заказе поставщику #%d", ErrNotFound, r.LineID, id)
+		}
+		if line.ReceivedQuantity+r.Quantity > line.Quantity {
+			return receipt, fmt.Errorf("%w: по строке %d заказано %s, уже принято %s", ErrConflict,
+				r.LineID, line.Quantity, line.ReceivedQuantity)
+		}
+		line.ReceivedQuantity += r.Quantity
+
+		unitCost := math.Round(line.PackPrice/line.PackSize.Float64()*10000) / 10000
+		if r.UnitCost != nil {
+			unitCost = *r.UnitCost
+		}
+		lineID := line.ID
+		receipt.Lines = append(receipt.Lines, models.GoodsReceiptLine{
+			InventoryID:         line.InventoryID,
+			Quantity:            r.Quantity,
+			UnitCost:            unitCost,
+			ExpiresAt:           r.ExpiresAt,
+			PurchaseOrderLineID: &lineID,
+		})
+
+		_, err := tx.Exec(`UPDATE purchase_order_lines SET received_quantity = received_quantity + $1 WHERE id = $2`,
+			r.Quantity, line.ID)
+		if err != nil {
+			return receipt, fmt.Errorf("не удалось обновить строку заказа поставщику: %v", err)
+		}
+	}
+
+	receipt, err = createGoodsReceipt(tx, receipt)
+	if err != nil {
+		return receipt, err
+	}
+
+	newStatus := models.PurchaseOrderReceived
+	for _, line := range lines[id] {
+		if line.ReceivedQuantity < line.Quantity {
+			newStatus = models.PurchaseOrderPartiallyReceived
+			break
+		}
+	}
+	if _, err := tx.Exec(`UPDATE purchase_orders SET status = $1 WHERE id = $2`, newStatus, id); err != nil {
+		return receipt, fmt.Errorf("не удалось обновить статус заказа поставщику: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return receipt, fmt.Errorf("не удалось провести приёмку: %v", err)
+	}
+	return receipt, nil
+}
+
+// GetPurchaseOrders возвращает заказы поставщикам, при status != "" — только в этом статусе.
+func GetPurchaseOrders(status string) ([]models.PurchaseOrder, error) {
+	return getPurchaseOrders(0, status)
+}
+
+func GetPurchaseOrderByID(idStr string) (models.PurchaseOrder, error) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return models.PurchaseOrder{}, fmt.Errorf("неверный формат ID: %v", err)
+	}
+
+	orders, err := getPurchaseOrders(id, "")
+	if err != nil {
+		return models.PurchaseOrder{}, err
+	}
+	if len(orders) == 0 {
+		return models.PurchaseOrder{}, fmt.Errorf("%w: заказ поставщику с ID %d", ErrNotFound, id)
+	}
+	return orders[0], nil
+}
+
+func getPurchaseOrders(id int, status string) ([]models.PurchaseOrder, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	rows, err := dbConn.Query(`
+		SELECT po.id, po.supplier_id, s.name, po.status, COALESCE(po.note, ''),
+			po.created_at, po.sent_at, po.expected_at, po.closed_at
+		FROM purchase_orders po JOIN suppliers s ON s.id = po.supplier_id
+		WHERE ($1 = 0 OR po.id = $1) AND ($2 = '' OR po.status::TEXT = $2)
+		ORDER BY po.created_at DESC, po.id DESC`, id, status)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении заказов поставщикам: %v", err)
+	}
+	defer rows.Close()
+
+	var orders []models.PurchaseOrder
+	var ids []int64
+	for rows.Next() {
+		var po models.PurchaseOrder
+		err := rows.Scan(&po.ID, &po.SupplierID, &po.SupplierName, &po.Status, &po.Note,
+			&po.CreatedAt, &po.SentAt, &po.ExpectedAt, &po.ClosedAt)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании заказа поставщику: %v", err)
+		}
+		orders = append(orders, po)
+		ids = append(ids, int64(po.ID))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по заказам поставщикам: %v", err)
+	}
+
+	lines, err := getPurchaseOrderLines(dbConn, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range orders {
+		orders[i].Lines = lines[orders[i].ID]
+		var total float64
+		for _, l := range orders[i].Lines {
+			total += float64(l.Packs) * l.PackPrice
+		}
+		orders[i].Total = math.Round(total*100) / 100
+	}
+	return orders, nil
+}
+
+// getPurchaseOrderLines загружает строки заказов, сгруппированные по purchase_order_id.
+func getPurchaseOrderLines(q queryer, orderIDs []int64) (map[int][]models.PurchaseOrderLine, error) {
+	rows, err := q.Query(`SELECT l.purchase_order_id, l.id, l.inventory_id, i.name, l.packs, l.pack_size, l.pack_price,
+							  l.received_quantity
+						  FROM purchase_order_lines l JOIN inventory i ON i.id = l.inventory_id
+						  WHERE l.purchase_order_id = ANY($1) ORDER BY l.id`, pq.Array(orderIDs))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении строк заказов поставщикам: %v", err)
+	}
+	defer rows.Close()
+
+	result := make(map[int][]models.PurchaseOrderLine)
+	for rows.Next() {
+		var orderID int
+		var l models.PurchaseOrderLine
+		err := rows.Scan(&orderID, &l.ID, &l.InventoryID, &l.Name, &l.Packs, &l.PackSize, &l.PackPrice, &l.ReceivedQuantity)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании строки заказа поставщику: %v", err)
+		}
+		l.Quantity, err = l.PackSize.Mul(l.Packs)
+		if err != nil {
+			return nil, fmt.Errorf("строка заказа поставщику #%d: %v", l.ID, err)
+		}
+		result[orderID] = append(result[orderID], l)
+	}
+	return result, rows.Err()
+}
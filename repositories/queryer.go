@@ -0,0 +1,11 @@
+package repositories
+
+import "database/sql"
+
+// queryer — общее у *sql.DB и *sql.Tx, чтобы одни и те же запросы
+// можно было выполнять как отдельно, так и внутри транзакции.
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
@@ -0,0 +1,146 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+	"strconv"
+)
+
+// GetQueue возвращает заказы в статусах pending и preparing в порядке поступления
+// вместе с позициями, модификаторами и пожеланиями.
+func GetQueue() ([]models.QueueTicket, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	query := `
+	SELECT o.id, COALESCE(o.customer_id, 0), o.status, o.order_date, o.special_instructions,
+		o.assigned_staff_id, COALESCE(s.name, ''), o.claimed_at
+	FROM orders o
+	LEFT JOIN staff s ON s.id = o.assigned_staff_id
+	WHERE o.status IN ('pending', 'preparing')
+	ORDER BY o.order_date, o.id`
+
+	rows, err := dbConn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении очереди: %v", err)
+	}
+	defer rows.Close()
+
+	queue := []models.QueueTicket{}
+	var ids []int64
+	for rows.Next() {
+		var t models.QueueTicket
+		var specialInstructions sql.NullString
+		var staffID sql.NullInt64
+		var claimedAt sql.NullTime
+
+		err := rows.Scan(&t.OrderID, &t.CustomerID, &t.Status, &t.OrderDate, &specialInstructions,
+			&staffID, &t.AssignedStaffName, &claimedAt)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании заказа очереди: %v", err)
+		}
+
+		if specialInstructions.Valid {
+			if err := json.Unmarshal([]byte(specialInstructions.String), &t.SpecialInstructions); err != nil {
+				return nil, fmt.Errorf("не удалось распарсить special_instructions: %v", err)
+			}
+		}
+		if staffID.Valid {
+			id := int(staffID.Int64)
+			t.AssignedStaffID = &id
+		}
+		if claimedAt.Valid {
+			t.ClaimedAt = &claimedAt.Time
+		}
+
+		queue = append(queue, t)
+		ids = append(ids, int64(t.OrderID))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по очереди: %v", err)
+	}
+
+	details, err := getOrderItemDetails(dbConn, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range queue {
+		queue[i].Items = details[queue[i].OrderID]
+	}
+
+	return queue, nil
+}
+
+// ClaimTicket закрепляет заказ за сотрудником и переводит его в preparing.
+// Условие status = 'pending' проверяется в самом UPDATE, поэтому из двух
+// одновременных попыток успешной будет только одна.
+func ClaimTicket(orderIDStr string, staffID int) error {
+	orderID, err := strconv.Atoi(orderIDStr)
+	if err != nil {
+		return fmt.Errorf("неверный формат ID: %v", err)
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	var role string
+	err = dbConn.QueryRow(`SELECT role FROM staff WHERE id = $1`, staffID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: сотрудник с ID %d", ErrNotFound, staffID)
+	} else if err != nil {
+		return fmt.Errorf("ошибка при проверке сотрудника: %v", err)
+	}
+	if role == "cashier" {
+		return fmt.Errorf("%w: кассир не может брать заказы в работу", ErrConflict)
+	}
+
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return fmt.Errorf("не удалось начать транзакцию: %v", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE orders SET status = 'preparing', assigned_staff_id = $1, claimed_at = NOW()
+							WHERE id = $2 AND status = 'pending'`, staffID, orderID)
+	if err != nil {
+		return fmt.Errorf("ошибка при взятии заказа: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("не удалось получить результат обновления: %v", err)
+	}
+	if affected == 0 {
+		var status string
+		var assigned sql.NullString
+		err := tx.QueryRow(`SELECT o.status, s.name FROM orders o LEFT JOIN staff s ON s.id = o.assigned_staff_id
+							WHERE o.id = $1`, orderID).Scan(&status, &assigned)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: заказ с ID %d", ErrNotFound, orderID)
+		} else if err != nil {
+			return fmt.Errorf("ошибка при проверке заказа: %v", err)
+		}
+		if assigned.Valid {
+			return fmt.Errorf("%w: заказ #%d уже взял %s (статус %s)", ErrConflict, orderID, assigned.String, status)
+		}
+		return fmt.Errorf("%w: заказ #%d в статусе %s", ErrConflict, orderID, status)
+	}
+
+	if err := CreateOrderStatusHistory(tx, orderID, "preparing"); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("не удалось сохранить взятие заказа: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+	"frappuccino/utils"
+
+	"github.com/lib/pq"
+)
+
+// GetReceipt собирает данные чека: заказ с расчётом, позиции с размерами,
+// модификаторами и пожеланиями, и оплаты.
+func GetReceipt(idStr string) (models.Receipt, error) {
+	order, err := GetOrderById(idStr)
+	if err != nil {
+		return models.Receipt{}, err
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return models.Receipt{}, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	details, err := getOrderItemDetails(dbConn, []int64{int64(order.ID)})
+	if err != nil {
+		return models.Receipt{}, err
+	}
+	receipt := models.Receipt{Order: order, Items: details[order.ID]}
+
+	receipt.Payments, err = getPayments(dbConn, order.ID)
+	if err != nil {
+		return models.Receipt{}, err
+	}
+
+	var paid int64
+	for _, p := range receipt.Payments {
+		paid += utils.ToCents(p.Amount)
+	}
+	receipt.PaidTotal = utils.FromCents(paid)
+
+	return receipt, nil
+}
+
+// getOrderItemDetails загружает позиции заказов в читаемом виде, сгруппированные по order_id.
+func getOrderItemDetails(q queryer, orderIDs []int64) (map[int][]models.OrderItemDetail, error) {
+	query := `
+	SELECT oi.order_id, oi.id, mi.name, COALESCE(v.size::TEXT, mi.size::TEXT, ''), oi.quantity, oi.price_at_order_time, oi.customization,
+		COALESCE((SELECT array_agg(CASE WHEN oim.quantity > 1 THEN m.name || ' x' || oim.quantity ELSE m.name END ORDER BY oim.id)
+				  FROM order_item_modifiers oim JOIN modifiers m ON m.id = oim.modifier_id
+				  WHERE oim.order_item_id = oi.id), ARRAY[]::TEXT[])
+	FROM order_items oi
+	JOIN menu_items mi ON mi.id = oi.menu_item_id
+	LEFT JOIN menu_item_variants v ON v.id = oi.variant_id
+	WHERE oi.order_id = ANY($1)
+	ORDER BY oi.order_id, oi.id`
+
+	rows, err := q.Query(query, pq.Array(orderIDs))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении позиций заказа: %v", err)
+	}
+	defer rows.Close()
+
+	result := make(map[int][]models.OrderItemDetail)
+	for rows.Next() {
+		var orderID int
+		var item models.OrderItemDetail
+		var customization sql.NullString
+		var modifiers []string
+		if err := rows.Scan(&orderID, &item.OrderItemID, &item.Name, &item.Size, &item.Quantity, &item.UnitPrice,
+			&customization, pq.Array(&modifiers)); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании позиции: %v", err)
+		}
+		if customization.Valid {
+			if err := json.Unmarshal([]byte(customization.String), &item.Customization); err != nil {
+				return nil, fmt.Errorf("не удалось распарсить кастомизацию: %v", err)
+			}
+		}
+		item.Modifiers = modifiers
+		item.LineTotal = utils.FromCents(utils.ToCents(item.UnitPrice) * int64(item.Quantity))
+		result[orderID] = append(result[orderID], item)
+	}
+
+	return result, rows.Err()
+}
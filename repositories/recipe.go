@@ -0,0 +1,136 @@
+package repositories
+
+import (
+	"fmt"
+	"frappuccino/models"
+	"sort"
+)
+
+// Рецепт одной порции: собственный рецепт варианта, если он есть,
+// иначе базовый рецепт блюда, умноженный на recipe_multiplier варианта.
+const recipeQuery = `
+	SELECT r.ingredient_id, i.name, SUM(r.quantity_required)
+	FROM (
+		SELECT vi.ingredient_id, vi.quantity_required
+		FROM menu_item_variant_ingredients vi
+		WHERE vi.variant_id = $2
+		UNION ALL
+		SELECT mii.ingredient_id,
+			mii.quantity_required * COALESCE(
+				(SELECT v.recipe_multiplier FROM menu_item_variants v WHERE v.id = $2), 1)
+		FROM menu_item_ingredients mii
+		WHERE mii.menu_item_id = $1
+		  AND NOT EXISTS (SELECT 1 FROM menu_item_variant_ingredients vi WHERE vi.variant_id = $2)
+	) r
+	JOIN inventory i ON i.id = r.ingredient_id
+	GROUP BY r.ingredient_id, i.name
+	ORDER BY r.ingredient_id`
+
+// ResolveRecipe возвращает ингредиенты, которые уйдут на позицию заказа
+// с учётом выбранного размера, модификаторов и количества порций.
+func ResolveRecipe(q queryer, item models.OrderItem) ([]models.RecipeLine, error) {
+	rows, err := q.Query(recipeQuery, item.MenuItemID, item.VariantID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении рецепта: %v", err)
+	}
+	defer rows.Close()
+
+	var lines []models.RecipeLine
+	for rows.Next() {
+		var line models.RecipeLine
+		if err := rows.Scan(&line.IngredientID, &line.Name, &line.Quantity); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании рецепта: %v", err)
+		}
+		lines = append(lines, line)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по рецепту: %v", err)
+	}
+
+	if len(item.Modifiers) > 0 {
+		lines, err = applyModifiers(q, lines, item.Modifiers)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Модификаторы добавляют ингредиенты в конец; списание блокирует строки
+	// inventory в порядке рецепта, а везде строки блокируются по возрастанию id
+	sort.Slice(lines, func(i, j int) bool { return lines[i].IngredientID < lines[j].IngredientID })
+
+	for i := range lines {
+		lines[i].Quantity, err = lines[i].Quantity.Mul(item.Quantity)
+		if err != nil {
+			return nil, fmt.Errorf("ингредиент #%d: %v", lines[i].IngredientID, err)
+		}
+	}
+
+	return lines, nil
+}
+
+// applyModifiers меняет рецепт одной порции: сначала замены ингредиентов,
+// затем добавки (добавка умножается на количество модификатора).
+func applyModifiers(q queryer, lines []models.RecipeLine, selected []models.OrderItemModifier) ([]models.RecipeLine, error) {
+	ids := make([]int64, 0, len(selected))
+	for _, m := range selected {
+		ids = append(ids, int64(m.ModifierID))
+	}
+
+	ingredients, err := getModifierIngredients(q, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	pos := make(map[int]int)
+	for i, line := range lines {
+		pos[line.IngredientID] = i
+	}
+	add := func(id int, name string, qty models.Quantity) {
+		if i, ok := pos[id]; ok {
+			lines[i].Quantity += qty
+			return
+		}
+		pos[id] = len(lines)
+		lines = append(lines, models.RecipeLine{IngredientID: id, Name: name, Quantity: qty})
+	}
+
+	for _, m := range selected {
+		for _, ing := range ingredients[m.ModifierID] {
+			if ing.ReplacesIngredientID == nil {
+				continue
+			}
+			i, ok := pos[*ing.ReplacesIngredientID]
+			if !ok {
+				continue // заменять нечего
+			}
+			qty := lines[i].Quantity
+			if ing.Quantity != nil {
+				qty = *ing.Quantity
+			}
+			lines[i].Quantity = 0
+			add(ing.IngredientID, ing.Name, qty)
+		}
+	}
+
+	for _, m := range selected {
+		for _, ing := range ingredients[m.ModifierID] {
+			if ing.ReplacesIngredientID != nil || ing.Quantity == nil {
+				continue
+			}
+			qty, err := ing.Quantity.Mul(m.Quantity)
+			if err != nil {
+				return nil, fmt.Errorf("модификатор #%d: %v", m.ModifierID, err)
+			}
+			add(ing.IngredientID, ing.Name, qty)
+		}
+	}
+
+	result := lines[:0]
+	for _, line := range lines {
+		if line.Quantity != 0 {
+			result = append(result, line)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+	"frappuccino/utils"
+)
+
+// DefaultReorderDays — за сколько дней считать средний расход.
+const DefaultReorderDays = 14
+
+// GetReorderSuggestions собирает по каждому активному ингредиенту остаток,
+// уже заказанное у поставщиков, расход по заказам за days дней и самого
+// дешёвого (за единицу) активного поставщика, и возвращает только то, что
+// нужно дозаказать. Срок поставки берётся из inventory.lead_time_days,
+// иначе — у поставщика, иначе 1 день.
+func GetReorderSuggestions(days int) ([]models.ReorderSuggestion, error) {
+	if days <= 0 {
+		days = DefaultReorderDays
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	query := `
+	WITH usage AS (
+		SELECT inventory_id, -SUM(change_amount) AS used
+		FROM inventory_transactions
+		WHERE reference_type = 'order' AND transaction_date >= NOW() - make_interval(days => $1)
+		GROUP BY inventory_id
+	),
+	on_order AS (
+		SELECT l.inventory_id, SUM(GREATEST(l.packs * l.pack_size - l.received_quantity, 0)) AS qty
+		FROM purchase_order_lines l
+		JOIN purchase_orders po ON po.id = l.purchase_order_id
+		WHERE po.status IN ('sent', 'partially_received')
+		GROUP BY l.inventory_id
+	),
+	best AS (
+		SELECT DISTINCT ON (si.inventory_id)
+			si.inventory_id, s.id AS supplier_id, s.name, COALESCE(si.supplier_sku, '') AS sku,
+			si.pack_size, si.pack_price, s.lead_time_days
+		FROM supplier_items si
+		JOIN suppliers s ON s.id = si.supplier_id
+		WHERE s.active
+		ORDER BY si.inventory_id, si.pack_price / si.pack_size, s.id
+	)
+	SELECT i.id, i.name, i.unit, i.quantity, i.price_per_unit,
+		COALESCE(oo.qty, 0), COALESCE(i.par_level, 0), COALESCE(i.lead_time_days, b.lead_time_days, 1),
+		COALESCE(u.used, 0), b.supplier_id, COALESCE(b.name, ''), COALESCE(b.sku, ''),
+		COALESCE(b.pack_size, 0), COALESCE(b.pack_price, 0)
+	FROM inventory i
+	LEFT JOIN usage u ON u.inventory_id = i.id
+	LEFT JOIN on_order oo ON oo.inventory_id = i.id
+	LEFT JOIN best b ON b.inventory_id = i.id
+	WHERE i.archived_at IS NULL
+	ORDER BY COALESCE(b.name, ''), i.name`
+
+	rows, err := dbConn.Query(query, days)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при расчёте предложений дозаказа: %v", err)
+	}
+	defer rows.Close()
+
+	suggestions := []models.ReorderSuggestion{}
+	for rows.Next() {
+		var s models.ReorderSuggestion
+		var used models.Quantity
+		var unitPrice float64
+		err := rows.Scan(&s.InventoryID, &s.Name, &s.Unit, &s.Quantity, &unitPrice,
+			&s.OnOrder, &s.ParLevel, &s.LeadTimeDays,
+			&used, &s.SupplierID, &s.SupplierName, &s.SupplierSKU,
+			&s.PackSize, &s.PackPrice)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании предложения дозаказа: %v", err)
+		}
+		needed, err := utils.ApplyReorderRule(&s, used, days, unitPrice)
+		if err != nil {
+			return nil, fmt.Errorf("ингредиент #%d: %v", s.InventoryID, err)
+		}
+		if needed {
+			suggestions = append(suggestions, s)
+		}
+	}
+
+	return suggestions, rows.Err()
+}
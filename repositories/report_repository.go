@@ -0,0 +1,181 @@
+package repositories
+
+import (
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// GetDiscountReport возвращает стоимость скидок по акциям за период [from, to).
+// Отменённые заказы не учитываются.
+func GetDiscountReport(from, to time.Time) ([]models.DiscountReportRow, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	query := `
+	SELECT p.id, p.name, COALESCE(p.code, ''), COUNT(DISTINCT od.order_id), SUM(od.amount)
+	FROM order_discounts od
+	JOIN orders o ON o.id = od.order_id
+	JOIN promotions p ON p.id = od.promotion_id
+	WHERE o.status <> 'canceled' AND o.order_date >= $1 AND o.order_date < $2
+	GROUP BY p.id, p.name, p.code
+	ORDER BY SUM(od.amount) DESC`
+
+	rows, err := dbConn.Query(query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при построении отчёта по скидкам: %v", err)
+	}
+	defer rows.Close()
+
+	report := []models.DiscountReportRow{}
+	for rows.Next() {
+		var row models.DiscountReportRow
+		if err := rows.Scan(&row.PromotionID, &row.Name, &row.Code, &row.Orders, &row.DiscountTotal); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании отчёта: %v", err)
+		}
+		report = append(report, row)
+	}
+
+	return report, rows.Err()
+}
+
+// statusSegments — отрезки времени, которые заказ провёл в каждом статусе:
+// от перехода в статус до следующего перехода. Конечные статусы отрезков не дают.
+const statusSegments = `
+	events AS (
+		SELECT o.id AS order_id, 'pending'::order_status AS status, o.order_date AS changed_at
+		FROM orders o
+		WHERE NOT EXISTS (SELECT 1 FROM order_status_history h WHERE h.order_id = o.id AND h.status = 'pending')
+		UNION ALL
+		SELECT order_id, status, changed_at FROM order_status_history
+	),
+	segments AS (
+		SELECT order_id, status, changed_at AS entered_at,
+			LEAD(changed_at) OVER (PARTITION BY order_id ORDER BY changed_at) AS left_at
+		FROM events
+	)`
+
+// Разрезы отчёта: значение разреза и порядок сортировки для отрезка. Час и день
+// недели берутся в часовом поясе приложения, как и границы from/to, — у сессии БД
+// он может быть другим.
+var statusDurationSlices = map[string]func(enteredAt time.Time, category string) (string, int){
+	"": func(time.Time, string) (string, int) { return "", 0 },
+	"hour": func(enteredAt time.Time, _ string) (string, int) {
+		hour := enteredAt.In(time.Local).Hour()
+		return strconv.Itoa(hour), hour
+	},
+	"dow": func(enteredAt time.Time, _ string) (string, int) {
+		weekday := enteredAt.In(time.Local).Weekday()
+		order := int(weekday)
+		if weekday == time.Sunday {
+			order = 7 // неделя с понедельника
+		}
+		return weekday.String(), order
+	},
+	"category": func(_ time.Time, category string) (string, int) { return category, 0 },
+}
+
+// IsValidStatusDurationSlice проверяет параметр group_by отчёта.
+func IsValidStatusDurationSlice(groupBy string) bool {
+	_, ok := statusDurationSlices[groupBy]
+	return ok
+}
+
+type statusDurationKey struct {
+	status string
+	slice  string
+	order  int
+}
+
+// GetStatusDurations считает среднее, медиану и 90-й перцентиль времени в каждом статусе
+// для переходов в статус в периоде [from, to). groupBy: "", hour, dow или category.
+func GetStatusDurations(from, to time.Time, groupBy string) ([]models.StatusDurationRow, error) {
+	sliceOf, ok := statusDurationSlices[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("неизвестный разрез %q", groupBy)
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	category, categoryJoin := `''`, ""
+	if groupBy == "category" {
+		// Заказ с позициями разных категорий попадает в каждую из них один раз
+		category = `c.category`
+		categoryJoin = `
+		JOIN (SELECT DISTINCT oi.order_id, UNNEST(mi.category) AS category
+			  FROM order_items oi JOIN menu_items mi ON mi.id = oi.menu_item_id) c ON c.order_id = s.order_id`
+	}
+
+	query := `WITH` + statusSegments + `
+	SELECT s.status::TEXT, ` + category + `, s.entered_at, EXTRACT(EPOCH FROM s.left_at - s.entered_at)
+	FROM segments s` + categoryJoin + `
+	WHERE s.left_at IS NOT NULL AND s.entered_at >= $1 AND s.entered_at < $2`
+
+	rows, err := dbConn.Query(query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при построении отчёта по статусам: %v", err)
+	}
+	defer rows.Close()
+
+	groups := make(map[statusDurationKey][]float64)
+	for rows.Next() {
+		var status, category string
+		var enteredAt time.Time
+		var secs float64
+		if err := rows.Scan(&status, &category, &enteredAt, &secs); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании отчёта: %v", err)
+		}
+		slice, order := sliceOf(enteredAt, category)
+		key := statusDurationKey{status: status, slice: slice, order: order}
+		groups[key] = append(groups[key], secs)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по отчёту: %v", err)
+	}
+
+	keys := make([]statusDurationKey, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.status != b.status {
+			return a.status < b.status
+		}
+		if a.order != b.order {
+			return a.order < b.order
+		}
+		return a.slice < b.slice
+	})
+
+	report := []models.StatusDurationRow{}
+	for _, key := range keys {
+		secs := groups[key]
+		var sum float64
+		for _, v := range secs {
+			sum += v
+		}
+		report = append(report, models.StatusDurationRow{
+			Status:        key.status,
+			Slice:         key.slice,
+			Samples:       len(secs),
+			AvgSeconds:    math.Round(sum / float64(len(secs))),
+			MedianSeconds: math.Round(utils.Percentile(secs, 0.5)),
+			P90Seconds:    math.Round(utils.Percentile(secs, 0.9)),
+		})
+	}
+
+	return report, nil
+}
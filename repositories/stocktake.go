@@ -0,0 +1,381 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+	"math"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+// CreateStocktake открывает инвентаризацию и фиксирует учётные остатки.
+// Одновременно может быть открыта только одна инвентаризация.
+func CreateStocktake(st models.Stocktake) (int, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return 0, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("не удалось начать транзакцию: %v", err)
+	}
+	defer tx.Rollback()
+
+	var openID int
+	err = tx.QueryRow(`SELECT id FROM stocktakes WHERE status = 'open'`).Scan(&openID)
+	if err == nil {
+		return 0, fmt.Errorf("%w: инвентаризация #%d ещё не завершена", ErrConflict, openID)
+	} else if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("ошибка при проверке открытых инвентаризаций: %v", err)
+	}
+
+	var id int
+	err = tx.QueryRow(`INSERT INTO stocktakes (note) VALUES (NULLIF($1, '')) RETURNING id`, st.Note).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("не удалось создать инвентаризацию: %v", err)
+	}
+
+	ids := make([]int64, 0, len(st.InventoryIDs))
+	seen := make(map[int]bool)
+	for _, inventoryID := range st.InventoryIDs {
+		if !seen[inventoryID] {
+			seen[inventoryID] = true
+			ids = append(ids, int64(inventoryID))
+		}
+	}
+
+	result, err := tx.Exec(`INSERT INTO stocktake_lines (stocktake_id, inventory_id, system_quantity, unit_cost)
+							SELECT $1, id, quantity, price_per_unit FROM inventory
+							WHERE archived_at IS NULL AND (cardinality($2::INT[]) = 0 OR id = ANY($2))`,
+		id, pq.Array(ids))
+	if err != nil {
+		return 0, fmt.Errorf("не удалось зафиксировать остатки: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("не удалось получить результат: %v", err)
+	}
+	if len(ids) > 0 && affected != int64(len(ids)) {
+		return 0, fmt.Errorf("%w: часть ингредиентов не найдена или в архиве", ErrNotFound)
+	}
+	if affected == 0 {
+		return 0, fmt.Errorf("%w: на складе нет позиций для пересчёта", ErrConflict)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("не удалось сохранить инвентаризацию: %v", err)
+	}
+	return id, nil
+}
+
+// lockOpenStocktake блокирует инвентаризацию и проверяет, что она открыта.
+func lockOpenStocktake(q queryer, id int) error {
+	var status string
+	err := q.QueryRow(`SELECT status FROM stocktakes WHERE id = $1 FOR UPDATE`, id).Scan(&status)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: инвентаризация с ID %d", ErrNotFound, id)
+	} else if err != nil {
+		return fmt.Errorf("ошибка при получении инвентаризации: %v", err)
+	}
+	if status != models.StocktakeOpen {
+		return fmt.Errorf("%w: инвентаризация #%d в статусе %s", ErrConflict, id, status)
+	}
+	return nil
+}
+
+// RecordStocktakeCounts сохраняет посчитанные количества вместе с учётным остатком
+// на момент пересчёта: всё, что продали или приняли между стартом и пересчётом,
+// уже отражено и на полке, и в остатке. Можно вносить частями и исправлять уже
+// внесённые, пока инвентаризация открыта.
+func RecordStocktakeCounts(idStr string, counts []models.StocktakeCount) error {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return fmt.Errorf("неверный формат ID: %v", err)
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return fmt.Errorf("не удалось начать транзакцию: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockOpenStocktake(tx, id); err != nil {
+		return err
+	}
+
+	for _, c := range counts {
+		result, err := tx.Exec(`UPDATE stocktake_lines SET counted_quantity = $1, counted_at = NOW(),
+									expected_quantity = (SELECT quantity FROM inventory WHERE id = $3)
+								WHERE stocktake_id = $2 AND inventory_id = $3`, c.CountedQuantity, id, c.InventoryID)
+		if err != nil {
+			return fmt.Errorf("не удалось сохранить пересчёт ингредиента #%d: %v", c.InventoryID, err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("не удалось получить результат: %v", err)
+		}
+		if affected == 0 {
+			return fmt.Errorf("%w: ингредиент %d не входит в инвентаризацию #%d", ErrNotFound, c.InventoryID, id)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("не удалось сохранить пересчёт: %v", err)
+	}
+	return nil
+}
+
+// ApproveStocktake проводит расхождения по посчитанным позициям: текущий остаток
+// меняется на (посчитано - учётный остаток в момент пересчёта), не ниже нуля,
+// с записью в журнал по цене на старте. Движения после пересчёта сохраняются.
+// Непосчитанные позиции не меняются.
+func ApproveStocktake(idStr string) (models.Stocktake, error) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return models.Stocktake{}, fmt.Errorf("неверный формат ID: %v", err)
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return models.Stocktake{}, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return models.Stocktake{}, fmt.Errorf("не удалось начать транзакцию: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockOpenStocktake(tx, id); err != nil {
+		return models.Stocktake{}, err
+	}
+
+	lines, err := getStocktakeLines(tx, []int64{int64(id)})
+	if err != nil {
+		return models.Stocktake{}, err
+	}
+
+	for _, line := range lines[id] {
+		if line.Variance == nil || *line.Variance == 0 {
+			continue
+		}
+
+		var before models.Quantity
+		err := tx.QueryRow(`SELECT quantity FROM inventory WHERE id = $1 FOR UPDATE`, line.InventoryID).Scan(&before)
+		if err != nil {
+			return models.Stocktake{}, fmt.Errorf("ошибка при получении остатка ингредиента #%d: %v", line.InventoryID, err)
+		}
+		// Сначала списываем просроченные партии, иначе недостача уйдёт на свежие,
+		// а просрочку фоновая задача спишет ещё раз
+		if _, err := writeOffExpiredLots(tx, line.InventoryID); err != nil {
+			return models.Stocktake{}, err
+		}
+		var stock models.Quantity
+		err = tx.QueryRow(`SELECT quantity FROM inventory WHERE id = $1`, line.InventoryID).Scan(&stock)
+		if err != nil {
+			return models.Stocktake{}, fmt.Errorf("ошибка при получении остатка ингредиента #%d: %v", line.InventoryID, err)
+		}
+
+		delta := *line.Variance
+		// Списанная просрочка уже объясняет часть недостачи
+		if delta < 0 {
+			delta += before - stock
+			if delta > 0 {
+				delta = 0
+			}
+		}
+		if stock+delta < 0 {
+			delta = -stock
+		}
+		if delta == 0 {
+			continue
+		}
+
+		_, err = tx.Exec(`UPDATE inventory SET quantity = quantity + $1::NUMERIC, version = version + 1, last_updated = NOW()
+						  WHERE id = $2`, delta, line.InventoryID)
+		if err != nil {
+			return models.Stocktake{}, fmt.Errorf("не удалось скорректировать ингредиент #%d: %v", line.InventoryID, err)
+		}
+		// Недостача расходует партии; излишек остаётся без партии
+		if delta < 0 {
+			if err := consumeLots(tx, line.InventoryID, -delta); err != nil {
+				return models.Stocktake{}, err
+			}
+		}
+
+		err = recordInventoryTransaction(tx, models.InventoryTransaction{
+			InventoryID:   line.InventoryID,
+			ChangeAmount:  delta,
+			UnitCost:      line.UnitCost,
+			Reason:        fmt.Sprintf("Stocktake #%d", id),
+			ReferenceType: ReferenceStocktake,
+			ReferenceID:   &id,
+		})
+		if err != nil {
+			return models.Stocktake{}, err
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE stocktakes SET status = 'approved', approved_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		return models.Stocktake{}, fmt.Errorf("не удалось утвердить инвентаризацию: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Stocktake{}, fmt.Errorf("не удалось утвердить инвентаризацию: %v", err)
+	}
+	return GetStocktakeByID(idStr)
+}
+
+// CancelStocktake закрывает открытую инвентаризацию без изменения остатков.
+func CancelStocktake(idStr string) error {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return fmt.Errorf("неверный формат ID: %v", err)
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return fmt.Errorf("не удалось начать транзакцию: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockOpenStocktake(tx, id); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE stocktakes SET status = 'canceled', canceled_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("не удалось отменить инвентаризацию: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetStocktakes возвращает инвентаризации, при status != "" — только в этом статусе.
+func GetStocktakes(status string) ([]models.Stocktake, error) {
+	return getStocktakes(0, status)
+}
+
+func GetStocktakeByID(idStr string) (models.Stocktake, error) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return models.Stocktake{}, fmt.Errorf("неверный формат ID: %v", err)
+	}
+
+	stocktakes, err := getStocktakes(id, "")
+	if err != nil {
+		return models.Stocktake{}, err
+	}
+	if len(stocktakes) == 0 {
+		return models.Stocktake{}, fmt.Errorf("%w: инвентаризация с ID %d", ErrNotFound, id)
+	}
+	return stocktakes[0], nil
+}
+
+func getStocktakes(id int, status string) ([]models.Stocktake, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	rows, err := dbConn.Query(`
+		SELECT id, status, COALESCE(note, ''), started_at, approved_at, canceled_at
+		FROM stocktakes
+		WHERE ($1 = 0 OR id = $1) AND ($2 = '' OR status::TEXT = $2)
+		ORDER BY started_at DESC, id DESC`, id, status)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении инвентаризаций: %v", err)
+	}
+	defer rows.Close()
+
+	var stocktakes []models.Stocktake
+	var ids []int64
+	for rows.Next() {
+		var st models.Stocktake
+		if err := rows.Scan(&st.ID, &st.Status, &st.Note, &st.StartedAt, &st.ApprovedAt, &st.CanceledAt); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании инвентаризации: %v", err)
+		}
+		stocktakes = append(stocktakes, st)
+		ids = append(ids, int64(st.ID))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по инвентаризациям: %v", err)
+	}
+
+	lines, err := getStocktakeLines(dbConn, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range stocktakes {
+		stocktakes[i].Lines = lines[stocktakes[i].ID]
+		var cost float64
+		for _, l := range stocktakes[i].Lines {
+			if l.CountedQuantity != nil {
+				stocktakes[i].Counted++
+			}
+			if l.VarianceCost != nil {
+				cost += *l.VarianceCost
+			}
+		}
+		stocktakes[i].VarianceCost = math.Round(cost*100) / 100
+	}
+	return stocktakes, nil
+}
+
+// getStocktakeLines загружает строки инвентаризаций и считает расхождения.
+func getStocktakeLines(q queryer, stocktakeIDs []int64) (map[int][]models.StocktakeLine, error) {
+	rows, err := q.Query(`SELECT l.stocktake_id, l.inventory_id, i.name, COALESCE(i.unit::TEXT, ''), l.system_quantity,
+							  l.counted_quantity, l.counted_at, COALESCE(l.expected_quantity, l.system_quantity), l.unit_cost
+						  FROM stocktake_lines l JOIN inventory i ON i.id = l.inventory_id
+						  WHERE l.stocktake_id = ANY($1) ORDER BY i.name, l.id`, pq.Array(stocktakeIDs))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении строк инвентаризаций: %v", err)
+	}
+	defer rows.Close()
+
+	result := make(map[int][]models.StocktakeLine)
+	for rows.Next() {
+		var stocktakeID int
+		var l models.StocktakeLine
+		var unitCost sql.NullFloat64
+		var expected models.Quantity
+		err := rows.Scan(&stocktakeID, &l.InventoryID, &l.Name, &l.Unit, &l.SystemQuantity,
+			&l.CountedQuantity, &l.CountedAt, &expected, &unitCost)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании строки инвентаризации: %v", err)
+		}
+		if unitCost.Valid {
+			l.UnitCost = &unitCost.Float64
+		}
+		if l.CountedQuantity != nil {
+			l.ExpectedQuantity = &expected
+			variance := *l.CountedQuantity - expected
+			l.Variance = &variance
+			if l.UnitCost != nil {
+				cost := math.Round(variance.Float64()**l.UnitCost*100) / 100
+				l.VarianceCost = &cost
+			}
+		}
+		result[stocktakeID] = append(result[stocktakeID], l)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,187 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+func CreateSupplier(s models.Supplier) (int, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return 0, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("не удалось начать транзакцию: %v", err)
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRow(`INSERT INTO suppliers (name, contact_name, phone, email, lead_time_days, active)
+					   VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), $5, $6) RETURNING id`,
+		s.Name, s.ContactName, s.Phone, s.Email, s.LeadTimeDays, s.Active).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("не удалось создать поставщика: %v", err)
+	}
+
+	if err := setSupplierItems(tx, id, s.Items); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("не удалось сохранить поставщика: %v", err)
+	}
+	return id, nil
+}
+
+// SetSupplierItems заменяет каталог поставщика целиком.
+func SetSupplierItems(idStr string, items []models.SupplierItem) error {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return fmt.Errorf("неверный формат ID: %v", err)
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return fmt.Errorf("не удалось начать транзакцию: %v", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM suppliers WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return fmt.Errorf("ошибка при проверке поставщика: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: поставщик с ID %d", ErrNotFound, id)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM supplier_items WHERE supplier_id = $1`, id); err != nil {
+		return fmt.Errorf("не удалось очистить каталог поставщика: %v", err)
+	}
+	if err := setSupplierItems(tx, id, items); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func setSupplierItems(q queryer, supplierID int, items []models.SupplierItem) error {
+	for _, item := range items {
+		_, err := q.Exec(`INSERT INTO supplier_items (supplier_id, inventory_id, supplier_sku, pack_size, pack_price)
+						  VALUES ($1, $2, NULLIF($3, ''), $4, $5)`,
+			supplierID, item.InventoryID, item.SupplierSKU, item.PackSize, item.PackPrice)
+		if err != nil {
+			return fmt.Errorf("не удалось добавить ингредиент %d в каталог: %v", item.InventoryID, err)
+		}
+	}
+	return nil
+}
+
+func GetSuppliers() ([]models.Supplier, error) {
+	return getSuppliers(0)
+}
+
+func GetSupplierByID(idStr string) (models.Supplier, error) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return models.Supplier{}, fmt.Errorf("неверный формат ID: %v", err)
+	}
+
+	suppliers, err := getSuppliers(id)
+	if err != nil {
+		return models.Supplier{}, err
+	}
+	if len(suppliers) == 0 {
+		return models.Supplier{}, fmt.Errorf("%w: поставщик с ID %d", ErrNotFound, id)
+	}
+	return suppliers[0], nil
+}
+
+// getSuppliers возвращает всех поставщиков (id == 0) или одного, вместе с каталогом.
+func getSuppliers(id int) ([]models.Supplier, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	rows, err := dbConn.Query(`SELECT id, name, COALESCE(contact_name, ''), COALESCE(phone, ''), COALESCE(email, ''),
+								   lead_time_days, active
+							   FROM suppliers WHERE $1 = 0 OR id = $1 ORDER BY id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении поставщиков: %v", err)
+	}
+	defer rows.Close()
+
+	var suppliers []models.Supplier
+	var ids []int64
+	for rows.Next() {
+		var s models.Supplier
+		if err := rows.Scan(&s.ID, &s.Name, &s.ContactName, &s.Phone, &s.Email, &s.LeadTimeDays, &s.Active); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании поставщика: %v", err)
+		}
+		suppliers = append(suppliers, s)
+		ids = append(ids, int64(s.ID))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по поставщикам: %v", err)
+	}
+
+	items, err := getSupplierItems(dbConn, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range suppliers {
+		suppliers[i].Items = items[suppliers[i].ID]
+	}
+	return suppliers, nil
+}
+
+func getSupplierItems(q queryer, supplierIDs []int64) (map[int][]models.SupplierItem, error) {
+	rows, err := q.Query(`SELECT si.supplier_id, si.inventory_id, i.name, COALESCE(si.supplier_sku, ''), si.pack_size, si.pack_price
+						  FROM supplier_items si JOIN inventory i ON i.id = si.inventory_id
+						  WHERE si.supplier_id = ANY($1) ORDER BY si.id`, pq.Array(supplierIDs))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении каталога поставщиков: %v", err)
+	}
+	defer rows.Close()
+
+	result := make(map[int][]models.SupplierItem)
+	for rows.Next() {
+		var supplierID int
+		var item models.SupplierItem
+		if err := rows.Scan(&supplierID, &item.InventoryID, &item.Name, &item.SupplierSKU, &item.PackSize, &item.PackPrice); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании позиции каталога: %v", err)
+		}
+		result[supplierID] = append(result[supplierID], item)
+	}
+	return result, rows.Err()
+}
+
+// getSupplierItem возвращает позицию каталога для заказа.
+func getSupplierItem(q queryer, supplierID, inventoryID int) (models.SupplierItem, error) {
+	item := models.SupplierItem{InventoryID: inventoryID}
+	err := q.QueryRow(`SELECT i.name, COALESCE(si.supplier_sku, ''), si.pack_size, si.pack_price
+					   FROM supplier_items si JOIN inventory i ON i.id = si.inventory_id
+					   WHERE si.supplier_id = $1 AND si.inventory_id = $2`,
+		supplierID, inventoryID).Scan(&item.Name, &item.SupplierSKU, &item.PackSize, &item.PackPrice)
+	if err == sql.ErrNoRows {
+		return item, fmt.Errorf("%w: ингредиента %d нет в каталоге поставщика %d", ErrNotFound, inventoryID, supplierID)
+	} else if err != nil {
+		return item, fmt.Errorf("ошибка при получении позиции каталога: %v", err)
+	}
+	return item, nil
+}
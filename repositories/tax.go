@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+
+	"github.com/lib/pq"
+)
+
+func CreateTaxRate(rate models.TaxRate) (int, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return 0, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	query := `INSERT INTO tax_rates (name, rate, category, inclusive, active)
+			  VALUES ($1, $2, NULLIF($3, ''), $4, $5) RETURNING id`
+
+	var id int
+	err = dbConn.QueryRow(query, rate.Name, rate.Rate, rate.Category, rate.Inclusive, rate.Active).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("не удалось создать налоговую ставку: %v", err)
+	}
+
+	return id, nil
+}
+
+func GetTaxRates() ([]models.TaxRate, error) {
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	return getTaxRates(dbConn, false)
+}
+
+func getTaxRates(q queryer, activeOnly bool) ([]models.TaxRate, error) {
+	rows, err := q.Query(`SELECT id, name, rate, COALESCE(category, ''), inclusive, active
+						  FROM tax_rates WHERE active OR NOT $1 ORDER BY id`, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении налоговых ставок: %v", err)
+	}
+	defer rows.Close()
+
+	var rates []models.TaxRate
+	for rows.Next() {
+		var rate models.TaxRate
+		if err := rows.Scan(&rate.ID, &rate.Name, &rate.Rate, &rate.Category, &rate.Inclusive, &rate.Active); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании налоговой ставки: %v", err)
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates, rows.Err()
+}
+
+// getOrderTaxLines загружает налоги заказов, сгруппированные по order_id.
+func getOrderTaxLines(q queryer, orderIDs []int64) (map[int][]models.OrderTaxLine, error) {
+	rows, err := q.Query(`SELECT order_id, tax_rate_id, name, rate, inclusive, taxable_amount, amount
+						  FROM order_tax_lines WHERE order_id = ANY($1) ORDER BY id`, pq.Array(orderIDs))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при запросе налогов заказа: %v", err)
+	}
+	defer rows.Close()
+
+	result := make(map[int][]models.OrderTaxLine)
+	for rows.Next() {
+		var orderID int
+		var t models.OrderTaxLine
+		if err := rows.Scan(&orderID, &t.TaxRateID, &t.Name, &t.Rate, &t.Inclusive, &t.TaxableAmount, &t.Amount); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании налога: %v", err)
+		}
+		result[orderID] = append(result[orderID], t)
+	}
+	return result, rows.Err()
+}
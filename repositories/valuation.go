@@ -0,0 +1,180 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+	"frappuccino/utils"
+	"math"
+	"time"
+)
+
+// GetInventoryValuation оценивает остатки методом average или fifo.
+//
+// Без asOf берутся текущие данные: для average — price_per_unit, для fifo —
+// остатки партий по их цене, а количество без партии — по price_per_unit.
+//
+// С asOf остатки восстанавливаются по журналу: начальный остаток — то, что
+// журналом не объясняется (текущий остаток минус сумма всех движений), по цене
+// первого движения; затем по порядку проводятся движения до asOf включительно.
+func GetInventoryValuation(method string, asOf *time.Time) (models.InventoryValuation, error) {
+	valuation := models.InventoryValuation{Method: method, Items: []models.InventoryValuationRow{}}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return valuation, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	var rows []models.InventoryValuationRow
+	if asOf == nil {
+		rows, err = currentValuation(dbConn, method)
+	} else {
+		valuation.AsOf = asOf.Format("2006-01-02")
+		// Остатки и журнал читаются двумя запросами — из одного снимка,
+		// чтобы заказ между ними не сдвинул начальный остаток
+		var tx *sql.Tx
+		tx, err = beginSnapshot(dbConn)
+		if err != nil {
+			return valuation, err
+		}
+		defer tx.Rollback()
+		rows, err = replayedValuation(tx, method, asOf.AddDate(0, 0, 1))
+	}
+	if err != nil {
+		return valuation, err
+	}
+
+	var total int64
+	for _, row := range rows {
+		if row.Quantity == 0 {
+			continue
+		}
+		if row.Quantity > 0 {
+			row.UnitCost = math.Round(row.Value/row.Quantity.Float64()*10000) / 10000
+		}
+		valuation.Items = append(valuation.Items, row)
+		total += utils.ToCents(row.Value)
+	}
+	valuation.Total = utils.FromCents(total)
+
+	return valuation, nil
+}
+
+func currentValuation(q queryer, method string) ([]models.InventoryValuationRow, error) {
+	rows, err := q.Query(`
+		SELECT i.id, i.name, COALESCE(i.unit::TEXT, ''), i.quantity, COALESCE(i.price_per_unit, 0),
+			COALESCE(l.quantity, 0), COALESCE(l.value, 0)
+		FROM inventory i
+		LEFT JOIN (
+			SELECT inventory_id, SUM(quantity) AS quantity, SUM(quantity * COALESCE(unit_cost, 0)) AS value
+			FROM inventory_lots WHERE quantity > 0
+			GROUP BY inventory_id
+		) l ON l.inventory_id = i.id
+		ORDER BY i.name, i.id`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении остатков: %v", err)
+	}
+	defer rows.Close()
+
+	var result []models.InventoryValuationRow
+	for rows.Next() {
+		var row models.InventoryValuationRow
+		var price, lotValue float64
+		var lotQuantity models.Quantity
+		err := rows.Scan(&row.InventoryID, &row.Name, &row.Unit, &row.Quantity, &price, &lotQuantity, &lotValue)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании остатка: %v", err)
+		}
+
+		switch {
+		case row.Quantity <= 0:
+			row.Value = 0
+		case method == models.ValuationFIFO:
+			// Партий может быть больше остатка, если количество правили вручную
+			if lotQuantity > row.Quantity {
+				lotValue = lotValue * row.Quantity.Float64() / lotQuantity.Float64()
+				lotQuantity = row.Quantity
+			}
+			row.Value = lotValue + (row.Quantity-lotQuantity).Float64()*price
+		default:
+			row.Value = row.Quantity.Float64() * price
+		}
+		row.Value = utils.FromCents(utils.ToCents(row.Value))
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+func replayedValuation(q queryer, method string, before time.Time) ([]models.InventoryValuationRow, error) {
+	items, err := q.Query(`SELECT id, name, COALESCE(unit::TEXT, ''), quantity, COALESCE(price_per_unit, 0)
+						   FROM inventory ORDER BY name, id`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении остатков: %v", err)
+	}
+	defer items.Close()
+
+	var result []models.InventoryValuationRow
+	current := make(map[int]models.Quantity)
+	prices := make(map[int]float64)
+	for items.Next() {
+		var row models.InventoryValuationRow
+		var price float64
+		if err := items.Scan(&row.InventoryID, &row.Name, &row.Unit, &row.Quantity, &price); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании остатка: %v", err)
+		}
+		current[row.InventoryID] = row.Quantity
+		prices[row.InventoryID] = price
+		result = append(result, row)
+	}
+	if err := items.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по остаткам: %v", err)
+	}
+
+	ledger, err := q.Query(`SELECT inventory_id, change_amount, unit_cost, transaction_date
+							FROM inventory_transactions
+							WHERE inventory_id IS NOT NULL
+							ORDER BY inventory_id, transaction_date, id`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении журнала движений: %v", err)
+	}
+	defer ledger.Close()
+
+	moves := make(map[int][]utils.StockMovement)
+	total := make(map[int]models.Quantity)
+	firstCost := make(map[int]float64)
+	for ledger.Next() {
+		var inventoryID int
+		var m utils.StockMovement
+		var unitCost sql.NullFloat64
+		var date time.Time
+		if err := ledger.Scan(&inventoryID, &m.Change, &unitCost, &date); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании движения: %v", err)
+		}
+		if unitCost.Valid {
+			m.UnitCost = &unitCost.Float64
+			if _, ok := firstCost[inventoryID]; !ok {
+				firstCost[inventoryID] = unitCost.Float64
+			}
+		}
+		total[inventoryID] += m.Change
+		if date.Before(before) {
+			moves[inventoryID] = append(moves[inventoryID], m)
+		}
+	}
+	if err := ledger.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по журналу движений: %v", err)
+	}
+
+	for i := range result {
+		id := result[i].InventoryID
+		cost, ok := firstCost[id]
+		if !ok {
+			cost = prices[id]
+		}
+		opening := current[id] - total[id]
+		result[i].Quantity, result[i].Value = utils.ReplayValuation(method, opening, cost, moves[id])
+	}
+	return result, nil
+}
@@ -0,0 +1,237 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"frappuccino/db"
+	"frappuccino/models"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// recordWaste сохраняет списание и пишет его в журнал. Остаток ингредиента
+// уменьшает вызывающий код в той же транзакции.
+func recordWaste(q queryer, entry models.WasteEntry) (models.WasteEntry, error) {
+	var note sql.NullString
+	if entry.Note != "" {
+		note = sql.NullString{String: entry.Note, Valid: true}
+	}
+
+	err := q.QueryRow(`INSERT INTO waste_entries (inventory_id, quantity, reason, note, unit_cost, lot_id)
+					   VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, recorded_at`,
+		entry.InventoryID, entry.Quantity, entry.Reason, note, entry.UnitCost, entry.LotID).Scan(&entry.ID, &entry.RecordedAt)
+	if err != nil {
+		return entry, fmt.Errorf("не удалось сохранить списание ингредиента #%d: %v", entry.InventoryID, err)
+	}
+
+	reason := fmt.Sprintf("Waste #%d: %s", entry.ID, entry.Reason)
+	if entry.LotID != nil {
+		reason = fmt.Sprintf("Waste #%d: expired lot #%d", entry.ID, *entry.LotID)
+	}
+	err = recordInventoryTransaction(q, models.InventoryTransaction{
+		InventoryID:   entry.InventoryID,
+		ChangeAmount:  -entry.Quantity,
+		UnitCost:      entry.UnitCost,
+		Reason:        reason,
+		ReferenceType: ReferenceWaste,
+		ReferenceID:   &entry.ID,
+	})
+	if err != nil {
+		return entry, err
+	}
+
+	if entry.UnitCost != nil {
+		entry.Cost = math.Round(entry.Quantity.Float64()**entry.UnitCost*100) / 100
+	}
+	return entry, nil
+}
+
+// RecordWaste списывает испорченный или потерянный ингредиент: уменьшает остаток
+// (не ниже нуля), расходует партии от самой старой и пишет журнал по текущей цене.
+// Сначала списываются просроченные партии; причиной expired вручную списывается
+// только остаток без партии.
+func RecordWaste(idStr string, entry models.WasteEntry) (models.WasteEntry, error) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return entry, fmt.Errorf("неверный формат ID: %v", err)
+	}
+	entry.InventoryID = id
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return entry, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return entry, fmt.Errorf("не удалось начать транзакцию: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Просроченные партии списываются отдельно, иначе ручное списание
+	// уменьшит свежие партии, а просрочка потом спишется второй раз
+	if _, err := writeOffExpiredLots(tx, id); err != nil {
+		return entry, err
+	}
+
+	var stock models.Quantity
+	var unitCost sql.NullFloat64
+	err = tx.QueryRow(`SELECT name, quantity, price_per_unit FROM inventory WHERE id = $1 FOR UPDATE`, id).
+		Scan(&entry.Name, &stock, &unitCost)
+	if err == sql.ErrNoRows {
+		return entry, fmt.Errorf("%w: ингредиент с ID %d", ErrNotFound, id)
+	} else if err != nil {
+		return entry, fmt.Errorf("ошибка при получении ингредиента #%d: %v", id, err)
+	}
+	if stock < entry.Quantity {
+		return entry, fmt.Errorf("%w: на складе %s %s, списать %s нельзя", ErrConflict, stock, entry.Name, entry.Quantity)
+	}
+	if unitCost.Valid {
+		entry.UnitCost = &unitCost.Float64
+	}
+
+	// Вручную просрочкой можно списать только остаток без партии: у партий срок
+	// известен, и истёкшие уже списаны выше, а оставшиеся партии свежие
+	if entry.Reason == models.WasteExpired {
+		var lotted models.Quantity
+		err = tx.QueryRow(`SELECT COALESCE(SUM(quantity), 0) FROM inventory_lots WHERE inventory_id = $1 AND quantity > 0`, id).
+			Scan(&lotted)
+		if err != nil {
+			return entry, fmt.Errorf("ошибка при получении партий ингредиента #%d: %v", id, err)
+		}
+		if untracked := stock - lotted; entry.Quantity > untracked {
+			return entry, fmt.Errorf("%w: просроченные партии %s списываются автоматически, без партии на складе %s",
+				ErrConflict, entry.Name, untracked)
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE inventory SET quantity = quantity - $1::NUMERIC, version = version + 1, last_updated = NOW()
+					  WHERE id = $2`, entry.Quantity, id)
+	if err != nil {
+		return entry, fmt.Errorf("не удалось списать ингредиент #%d: %v", id, err)
+	}
+	if entry.Reason != models.WasteExpired {
+		if err := consumeLots(tx, id, entry.Quantity); err != nil {
+			return entry, err
+		}
+	}
+
+	entry, err = recordWaste(tx, entry)
+	if err != nil {
+		return entry, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return entry, fmt.Errorf("не удалось сохранить списание: %v", err)
+	}
+	return entry, nil
+}
+
+// Разбивка отчёта по потерям: метка периода, в который попадает списание.
+// Период определяется в часовом поясе приложения, как и границы from/to, —
+// у сессии БД он может быть другим. Неделя начинается с понедельника.
+var wastePeriods = map[string]func(time.Time) string{
+	"": func(time.Time) string { return "" },
+	"day": func(t time.Time) string {
+		return t.In(time.Local).Format("2006-01-02")
+	},
+	"week": func(t time.Time) string {
+		t = t.In(time.Local)
+		monday := time.Date(t.Year(), t.Month(), t.Day()-(int(t.Weekday())+6)%7, 0, 0, 0, 0, time.Local)
+		return monday.Format("2006-01-02")
+	},
+	"month": func(t time.Time) string {
+		return t.In(time.Local).Format("2006-01")
+	},
+}
+
+// IsValidWastePeriod проверяет параметр period отчёта.
+func IsValidWastePeriod(period string) bool {
+	_, ok := wastePeriods[period]
+	return ok
+}
+
+type wasteReportKey struct {
+	period      string
+	inventoryID int
+	reason      string
+}
+
+// GetWasteReport считает потери за [from, to) по ингредиенту и причине,
+// с разбивкой по дням, неделям или месяцам. reason — необязательный фильтр.
+func GetWasteReport(from, to time.Time, period, reason string) ([]models.WasteReportRow, error) {
+	periodOf, ok := wastePeriods[period]
+	if !ok {
+		return nil, fmt.Errorf("неизвестный период %q", period)
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	query := `
+	SELECT w.recorded_at, w.inventory_id, i.name, COALESCE(i.unit::TEXT, ''), w.reason::TEXT,
+		w.quantity, w.quantity * COALESCE(w.unit_cost, 0)
+	FROM waste_entries w
+	JOIN inventory i ON i.id = w.inventory_id
+	WHERE w.recorded_at >= $1 AND w.recorded_at < $2 AND ($3 = '' OR w.reason::TEXT = $3)`
+
+	rows, err := dbConn.Query(query, from, to, reason)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при построении отчёта по потерям: %v", err)
+	}
+	defer rows.Close()
+
+	groups := make(map[wasteReportKey]*models.WasteReportRow)
+	for rows.Next() {
+		var recordedAt time.Time
+		var entry models.WasteReportRow
+		var cost float64
+		err := rows.Scan(&recordedAt, &entry.InventoryID, &entry.Name, &entry.Unit, &entry.Reason,
+			&entry.Quantity, &cost)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании отчёта: %v", err)
+		}
+
+		entry.Period = periodOf(recordedAt)
+		key := wasteReportKey{period: entry.Period, inventoryID: entry.InventoryID, reason: entry.Reason}
+		row := groups[key]
+		if row == nil {
+			row = &entry
+			row.Quantity = 0
+			groups[key] = row
+		}
+		row.Entries++
+		row.Quantity += entry.Quantity
+		row.Cost += cost
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по отчёту: %v", err)
+	}
+
+	report := make([]models.WasteReportRow, 0, len(groups))
+	for _, row := range groups {
+		row.Cost = math.Round(row.Cost*100) / 100
+		report = append(report, *row)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		a, b := report[i], report[j]
+		if a.Period != b.Period {
+			return a.Period < b.Period
+		}
+		if a.Cost != b.Cost {
+			return a.Cost > b.Cost
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Reason < b.Reason
+	})
+
+	return report, nil
+}
@@ -3,6 +3,7 @@ package router
 import (
 	"frappuccino/handlers"
 	"net/http"
+	"strings"
 )
 
 func SetupRouter() {
@@ -16,7 +17,32 @@ func SetupRouter() {
 		}
 	})
 
+	http.HandleFunc("/menu/export", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.ExportMenuCSVHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/menu/import", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handlers.ImportMenuCSVHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
 	http.HandleFunc("/menu/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/restore") {
+			if r.Method == http.MethodPost {
+				handlers.RestoreMenuItemHandler(w, r)
+			} else {
+				http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
 		if r.Method == http.MethodDelete {
 			handlers.DeleteMenuItemHandler(w, r)
 		} else if r.Method == http.MethodGet {
@@ -28,6 +54,16 @@ func SetupRouter() {
 		}
 	})
 
+	http.HandleFunc("/modifiers", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handlers.CreateModifierHandler(w, r)
+		} else if r.Method == http.MethodGet {
+			handlers.GetModifiersHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
 	http.HandleFunc("/inventory", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
 			handlers.CreateInventoryHandler(w, r)
@@ -38,7 +74,82 @@ func SetupRouter() {
 		}
 	})
 
+	http.HandleFunc("/inventory/receipts", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handlers.WithIdempotency("inventory-receipts", handlers.CreateGoodsReceiptHandler)(w, r)
+		} else if r.Method == http.MethodGet {
+			handlers.GetGoodsReceiptsHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/inventory/receipts/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetGoodsReceiptHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/inventory/export", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.ExportInventoryCSVHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/inventory/import", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handlers.ImportInventoryCSVHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/inventory/expiring", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetExpiringLotsHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/inventory/forecast", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetInventoryForecastHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/inventory/reorder-suggestions", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetReorderSuggestionsHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/inventory/reorder-suggestions/order-sheet", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handlers.ExportOrderSheetHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
 	http.HandleFunc("/inventory/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/waste") {
+			if r.Method == http.MethodPost {
+				handlers.WithIdempotency("inventory-waste", handlers.RecordWasteHandler)(w, r)
+			} else {
+				http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
 		if r.Method == http.MethodGet {
 			handlers.GetInventoryByIDHandler(w, r)
 		} else if r.Method == http.MethodPut {
@@ -54,13 +165,51 @@ func SetupRouter() {
 		if r.Method == http.MethodGet {
 			handlers.GetOrdersHandler(w, r)
 		} else if r.Method == http.MethodPost {
-			handlers.CreateOrderHandler(w, r)
+			handlers.WithIdempotency("orders", handlers.CreateOrderHandler)(w, r)
 		} else {
 			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
 		}
 	})
 
 	http.HandleFunc("/orders/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/tip") {
+			if r.Method == http.MethodPut {
+				handlers.UpdateOrderTipHandler(w, r)
+			} else {
+				http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/receipt") {
+			if r.Method == http.MethodGet {
+				handlers.GetOrderReceiptHandler(w, r)
+			} else {
+				http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/eta") {
+			if r.Method == http.MethodGet {
+				handlers.GetOrderETAHandler(w, r)
+			} else {
+				http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/payments") {
+			if r.Method == http.MethodGet {
+				handlers.GetPaymentsHandler(w, r)
+			} else if r.Method == http.MethodPost {
+				handlers.CreatePaymentHandler(w, r)
+			} else {
+				http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
 		if r.Method == http.MethodGet {
 			handlers.GetOrderByIDHandler(w, r)
 		} else if r.Method == http.MethodPut {
@@ -72,7 +221,7 @@ func SetupRouter() {
 
 	http.HandleFunc("/order-items", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
-			handlers.CreateOrderItemHandler(w, r)
+			handlers.WithIdempotency("order-items", handlers.CreateOrderItemHandler)(w, r)
 		} else {
 			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
 		}
@@ -96,6 +245,208 @@ func SetupRouter() {
 		}
 	})
 
+	http.HandleFunc("/queue", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetQueueHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/queue/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/claim") {
+			handlers.ClaimTicketHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/promotions", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetPromotionsHandler(w, r)
+		} else if r.Method == http.MethodPost {
+			handlers.CreatePromotionHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/tax-rates", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetTaxRatesHandler(w, r)
+		} else if r.Method == http.MethodPost {
+			handlers.CreateTaxRateHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/loyalty/rules", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetLoyaltyRulesHandler(w, r)
+		} else if r.Method == http.MethodPost {
+			handlers.CreateLoyaltyRuleHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/loyalty/tiers", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetLoyaltyTiersHandler(w, r)
+		} else if r.Method == http.MethodPost {
+			handlers.CreateLoyaltyTierHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/customers/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/loyalty/ledger") {
+			handlers.GetCustomerLedgerHandler(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/loyalty") {
+			handlers.GetCustomerLoyaltyHandler(w, r)
+		} else {
+			http.NotFound(w, r)
+		}
+	})
+
+	http.HandleFunc("/reports/discounts", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetDiscountReportHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/reports/status-durations", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetStatusDurationsHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/reports/inventory-valuation", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetInventoryValuationHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/reports/waste", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			handlers.GetWasteReportHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/suppliers", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handlers.CreateSupplierHandler(w, r)
+		} else if r.Method == http.MethodGet {
+			handlers.GetSuppliersHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/suppliers/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/items") {
+			if r.Method == http.MethodPut {
+				handlers.UpdateSupplierItemsHandler(w, r)
+			} else {
+				http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			handlers.GetSupplierHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/purchase-orders", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handlers.CreatePurchaseOrderHandler(w, r)
+		} else if r.Method == http.MethodGet {
+			handlers.GetPurchaseOrdersHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/purchase-orders/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/receive") {
+			if r.Method == http.MethodPost {
+				handlers.WithIdempotency("purchase-order-receipts", handlers.ReceivePurchaseOrderHandler)(w, r)
+			} else {
+				http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/send") || strings.HasSuffix(r.URL.Path, "/close") {
+			if r.Method == http.MethodPost {
+				handlers.PurchaseOrderActionHandler(w, r)
+			} else {
+				http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			handlers.GetPurchaseOrderHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/stocktakes", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handlers.CreateStocktakeHandler(w, r)
+		} else if r.Method == http.MethodGet {
+			handlers.GetStocktakesHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/stocktakes/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/counts") {
+			if r.Method == http.MethodPut {
+				handlers.RecordStocktakeCountsHandler(w, r)
+			} else {
+				http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/approve") || strings.HasSuffix(r.URL.Path, "/cancel") {
+			if r.Method == http.MethodPost {
+				handlers.StocktakeActionHandler(w, r)
+			} else {
+				http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			handlers.GetStocktakeHandler(w, r)
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+
 	err := http.ListenAndServe(":8080", nil)
 	if err != nil {
 		panic("Failed to start server: " + err.Error())
@@ -0,0 +1,56 @@
+package utils
+
+import "time"
+
+// ForecastSmoothing — коэффициент экспоненциального сглаживания: чем больше,
+// тем сильнее прогноз следует за последними днями.
+const ForecastSmoothing = 0.3
+
+// ForecastDemand прогнозирует дневной расход на horizon дней начиная с from.
+// history[i] — расход за день historyStart+i.
+//
+// Сезонность по дням недели: индекс дня = средний расход в этот день недели /
+// средний расход за день. Ряд очищается от сезонности, сглаживается
+// экспоненциально, и итоговый уровень умножается обратно на индекс дня.
+func ForecastDemand(history []float64, historyStart time.Time, from time.Time, horizon int, alpha float64) []float64 {
+	forecast := make([]float64, horizon)
+	if len(history) == 0 {
+		return forecast
+	}
+
+	var sums, counts [7]float64
+	var total float64
+	for i, v := range history {
+		wd := historyStart.AddDate(0, 0, i).Weekday()
+		sums[wd] += v
+		counts[wd]++
+		total += v
+	}
+	if total == 0 {
+		return forecast
+	}
+	mean := total / float64(len(history))
+
+	var index [7]float64
+	for wd := range index {
+		index[wd] = 1
+		if counts[wd] > 0 {
+			index[wd] = sums[wd] / counts[wd] / mean
+		}
+	}
+
+	level := mean
+	for i, v := range history {
+		idx := index[historyStart.AddDate(0, 0, i).Weekday()]
+		// В этот день недели расхода не бывает — уровень он не меняет
+		if idx == 0 {
+			continue
+		}
+		level = alpha*(v/idx) + (1-alpha)*level
+	}
+
+	for j := range forecast {
+		forecast[j] = level * index[from.AddDate(0, 0, j).Weekday()]
+	}
+	return forecast
+}
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// repeatWeeks повторяет недельный профиль (с понедельника) weeks раз.
+func repeatWeeks(week []float64, weeks int) []float64 {
+	var history []float64
+	for i := 0; i < weeks; i++ {
+		history = append(history, week...)
+	}
+	return history
+}
+
+func TestForecastDemand(t *testing.T) {
+	monday := time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)
+	from := monday.AddDate(0, 0, 14)
+
+	tests := []struct {
+		name    string
+		history []float64
+		alpha   float64
+		want    []float64 // прогноз на неделю с понедельника
+	}{
+		{
+			name:    "нет истории",
+			history: nil,
+			alpha:   ForecastSmoothing,
+			want:    []float64{0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name:    "расхода не было",
+			history: make([]float64, 14),
+			alpha:   ForecastSmoothing,
+			want:    []float64{0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name:    "ровный расход",
+			history: repeatWeeks([]float64{10, 10, 10, 10, 10, 10, 10}, 2),
+			alpha:   ForecastSmoothing,
+			want:    []float64{10, 10, 10, 10, 10, 10, 10},
+		},
+		{
+			name:    "в субботу расход вдвое больше",
+			history: repeatWeeks([]float64{10, 10, 10, 10, 10, 20, 10}, 2),
+			alpha:   ForecastSmoothing,
+			want:    []float64{10, 10, 10, 10, 10, 20, 10},
+		},
+		{
+			name:    "в воскресенье закрыто",
+			history: repeatWeeks([]float64{12, 12, 12, 12, 12, 12, 0}, 2),
+			alpha:   ForecastSmoothing,
+			want:    []float64{12, 12, 12, 12, 12, 12, 0},
+		},
+		{
+			// Индексы дней равны 1, уровень стартует со среднего 15 и за каждый
+			// день проходит половину пути к расходу этого дня.
+			name:    "сглаживание следует за ростом расхода",
+			history: append(repeatWeeks([]float64{10, 10, 10, 10, 10, 10, 10}, 1), repeatWeeks([]float64{20, 20, 20, 20, 20, 20, 20}, 1)...),
+			alpha:   0.5,
+			want: func() []float64 {
+				level := 10 + 5*math.Pow(0.5, 7)
+				level = 20 - (20-level)*math.Pow(0.5, 7)
+				return []float64{level, level, level, level, level, level, level}
+			}(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ForecastDemand(tt.history, monday, from, len(tt.want), tt.alpha)
+			if len(got) != len(tt.want) {
+				t.Fatalf("ForecastDemand() вернул %d дней, ожидалось %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if math.Abs(got[i]-tt.want[i]) > 1e-9 {
+					t.Errorf("день %d (%s): %.6f, ожидалось %.6f", i, from.AddDate(0, 0, i).Weekday(), got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"fmt"
+	"frappuccino/models"
+	"math"
+)
+
+// PointValueCents — сколько центов стоит один балл при оплате.
+const PointValueCents = 1
+
+// RedeemPoints списывает до points баллов как скидку на остаток позиций после акций.
+// Списываются только целые баллы и не больше, чем стоит заказ. Вызывается до AddTaxes,
+// чтобы налог считался от суммы после оплаты баллами. Возвращает число списанных баллов.
+func (b *PriceBreakdown) RedeemPoints(points int) int {
+	if points <= 0 {
+		return 0
+	}
+
+	var remaining int64
+	eligible := make([]int, 0, len(b.LineNet))
+	for i, net := range b.LineNet {
+		if net > 0 {
+			remaining += net
+			eligible = append(eligible, i)
+		}
+	}
+
+	used := int64(points)
+	if limit := remaining / PointValueCents; used > limit {
+		used = limit
+	}
+	if used == 0 {
+		return 0
+	}
+
+	amount := applyFixed(b.LineNet, eligible, used*PointValueCents)
+	b.Discount += amount
+	b.Discounts = append(b.Discounts, models.OrderDiscount{
+		Points:      int(used),
+		Description: pointsDiscountLabel(int(used)),
+		Amount:      FromCents(amount),
+	})
+
+	b.updateTotal()
+	return int(used)
+}
+
+// pointsDiscountLabel — подпись оплаты баллами. По-английски, как и остальные
+// строки чека: ESC/POS печатает только ASCII.
+func pointsDiscountLabel(points int) string {
+	return fmt.Sprintf("Points redeemed (%d)", points)
+}
+
+// EarnPoints считает баллы за заказ. net — сумма заказа после всех скидок (без налогов
+// и чаевых) в центах; она распределяется по позициям пропорционально их стоимости.
+// Каждое подходящее правило даёт PointsPerUnit баллов за единицу валюты позиции,
+// итог умножается на multiplier уровня и округляется вниз.
+func EarnPoints(lines []models.PricingLine, net int64, rules []models.LoyaltyRule, multiplier float64) int {
+	var subtotal int64
+	for _, line := range lines {
+		subtotal += ToCents(line.UnitPrice) * int64(line.Quantity)
+	}
+	if subtotal <= 0 || net <= 0 {
+		return 0
+	}
+
+	var points float64
+	for _, line := range lines {
+		lineNet := float64(ToCents(line.UnitPrice)*int64(line.Quantity)) * float64(net) / float64(subtotal)
+		for _, rule := range rules {
+			if !rule.Active {
+				continue
+			}
+			if rule.Category == "" || hasCategory(line.Categories, rule.Category) {
+				points += lineNet / 100 * rule.PointsPerUnit
+			}
+		}
+	}
+
+	// небольшой допуск, чтобы 17.999999 от деления не превращалось в 17
+	return int(math.Floor(points*multiplier + 1e-6))
+}
+
+// TierFor выбирает уровень по тратам: самый высокий, порог которого достигнут,
+// и следующий за ним. tiers должны быть отсортированы по MinSpend.
+func TierFor(tiers []models.LoyaltyTier, spend float64) (current, next *models.LoyaltyTier) {
+	for i := range tiers {
+		if ToCents(spend) >= ToCents(tiers[i].MinSpend) {
+			current = &tiers[i]
+		} else {
+			next = &tiers[i]
+			break
+		}
+	}
+	return current, next
+}
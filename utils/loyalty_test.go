@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"frappuccino/models"
+	"testing"
+)
+
+func TestEarnPoints(t *testing.T) {
+	base := models.LoyaltyRule{Name: "База", PointsPerUnit: 1, Active: true}
+	coffeeBonus := models.LoyaltyRule{Name: "Кофе", Category: "coffee", PointsPerUnit: 2, Active: true}
+	inactive := models.LoyaltyRule{Name: "Старое", PointsPerUnit: 10}
+
+	latte := models.PricingLine{Categories: []string{"coffee"}, UnitPrice: 4.5, Quantity: 2}
+	croissant := models.PricingLine{Categories: []string{"pastry"}, UnitPrice: 3, Quantity: 1}
+
+	tests := []struct {
+		name       string
+		lines      []models.PricingLine
+		net        int64
+		rules      []models.LoyaltyRule
+		multiplier float64
+		want       int
+	}{
+		{name: "балл за каждую единицу", lines: []models.PricingLine{latte}, net: 900,
+			rules: []models.LoyaltyRule{base}, multiplier: 1, want: 9},
+		{name: "множитель уровня округляется вниз", lines: []models.PricingLine{latte}, net: 900,
+			rules: []models.LoyaltyRule{base}, multiplier: 1.5, want: 13},
+		{name: "баллы считаются от суммы после скидки", lines: []models.PricingLine{latte}, net: 720,
+			rules: []models.LoyaltyRule{base}, multiplier: 1, want: 7},
+		// 1200 центов делятся 900:300 → 9 и 3; бонус 2×9 только за кофе
+		{name: "бонус за категорию только для её позиций", lines: []models.PricingLine{latte, croissant}, net: 1200,
+			rules: []models.LoyaltyRule{base, coffeeBonus}, multiplier: 1, want: 30},
+		{name: "множитель применяется к сумме правил", lines: []models.PricingLine{latte, croissant}, net: 1200,
+			rules: []models.LoyaltyRule{base, coffeeBonus}, multiplier: 1.25, want: 37},
+		{name: "неактивное правило не действует", lines: []models.PricingLine{latte}, net: 900,
+			rules: []models.LoyaltyRule{base, inactive}, multiplier: 1, want: 9},
+		// 100 центов делятся 100:350 → 0.2222… + 0.7777… = 0.9999999999999999 во float
+		{name: "деление по позициям не теряет балл",
+			lines: []models.PricingLine{{UnitPrice: 1, Quantity: 1}, {UnitPrice: 3.5, Quantity: 1}}, net: 100,
+			rules: []models.LoyaltyRule{base}, multiplier: 1, want: 1},
+		{name: "заказ оплачен полностью баллами", lines: []models.PricingLine{latte}, net: 0,
+			rules: []models.LoyaltyRule{base}, multiplier: 2, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EarnPoints(tt.lines, tt.net, tt.rules, tt.multiplier); got != tt.want {
+				t.Errorf("EarnPoints() = %d, ожидалось %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTierFor(t *testing.T) {
+	tiers := []models.LoyaltyTier{
+		{Name: "Bronze", MinSpend: 0, PointsMultiplier: 1},
+		{Name: "Silver", MinSpend: 100, PointsMultiplier: 1.25},
+		{Name: "Gold", MinSpend: 500, PointsMultiplier: 1.5},
+	}
+
+	tests := []struct {
+		name        string
+		tiers       []models.LoyaltyTier
+		spend       float64
+		wantCurrent string
+		wantNext    string
+	}{
+		{name: "без трат", tiers: tiers, spend: 0, wantCurrent: "Bronze", wantNext: "Silver"},
+		{name: "цента не хватает", tiers: tiers, spend: 99.99, wantCurrent: "Bronze", wantNext: "Silver"},
+		{name: "ровно порог", tiers: tiers, spend: 100, wantCurrent: "Silver", wantNext: "Gold"},
+		{name: "высший уровень", tiers: tiers, spend: 1000, wantCurrent: "Gold"},
+		{name: "первый уровень не достигнут", tiers: tiers[1:], spend: 10, wantNext: "Silver"},
+		{name: "уровней нет", spend: 10},
+	}
+
+	name := func(tier *models.LoyaltyTier) string {
+		if tier == nil {
+			return ""
+		}
+		return tier.Name
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, next := TierFor(tt.tiers, tt.spend)
+			if name(current) != tt.wantCurrent || name(next) != tt.wantNext {
+				t.Errorf("TierFor(%.2f) = %q, %q; ожидалось %q, %q",
+					tt.spend, name(current), name(next), tt.wantCurrent, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestEarnPointsWithTier(t *testing.T) {
+	tiers := []models.LoyaltyTier{
+		{Name: "Bronze", MinSpend: 0, PointsMultiplier: 1},
+		{Name: "Silver", MinSpend: 100, PointsMultiplier: 1.25},
+		{Name: "Gold", MinSpend: 500, PointsMultiplier: 1.5},
+	}
+	rules := []models.LoyaltyRule{{PointsPerUnit: 1, Active: true}}
+	lines := []models.PricingLine{{UnitPrice: 7.3, Quantity: 1}}
+
+	tests := []struct {
+		spend float64
+		want  int
+	}{
+		{spend: 50, want: 7},   // 7.3 × 1
+		{spend: 150, want: 9},  // 7.3 × 1.25 = 9.125
+		{spend: 600, want: 10}, // 7.3 × 1.5 = 10.95
+	}
+
+	for _, tt := range tests {
+		current, _ := TierFor(tiers, tt.spend)
+		if got := EarnPoints(lines, 730, rules, current.PointsMultiplier); got != tt.want {
+			t.Errorf("траты %.0f (%s): EarnPoints() = %d, ожидалось %d", tt.spend, current.Name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package utils
+
+import (
+	"fmt"
+	"frappuccino/models"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Все суммы в расчёте ведутся в центах (int64), чтобы результат не зависел
+// от порядка сложения float64. Округление — половина от нуля.
+
+func ToCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func FromCents(cents int64) float64 {
+	return float64(cents) / 100
+}
+
+// PriceBreakdown — результат расчёта заказа.
+// Total = Subtotal - Discount + налоги сверх цены + Tip.
+type PriceBreakdown struct {
+	Subtotal     int64
+	LineNet      []int64 // сумма каждой позиции после скидок, в порядке входных строк
+	Discounts    []models.OrderDiscount
+	Discount     int64
+	TaxLines     []models.OrderTaxLine
+	Tax          int64 // все налоги, включая уже входящие в цену
+	TaxExclusive int64 // налоги, которые добавляются к цене
+	Tip          int64
+	Total        int64
+}
+
+// PriceOrder считает подытог и применяет скидки по очереди (в порядке promos).
+// Каждая следующая скидка считается от того, что осталось от позиции после
+// предыдущих, поэтому суммарная скидка никогда не превышает подытог.
+func PriceOrder(lines []models.PricingLine, promos []models.Promotion, at time.Time) PriceBreakdown {
+	var b PriceBreakdown
+	b.LineNet = make([]int64, len(lines))
+	for i, line := range lines {
+		b.LineNet[i] = ToCents(line.UnitPrice) * int64(line.Quantity)
+		b.Subtotal += b.LineNet[i]
+	}
+
+	for _, promo := range promos {
+		if !PromotionApplies(promo, at) {
+			continue
+		}
+
+		eligible := make([]int, 0, len(lines))
+		for i, line := range lines {
+			if b.LineNet[i] > 0 && (promo.Category == "" || hasCategory(line.Categories, promo.Category)) {
+				eligible = append(eligible, i)
+			}
+		}
+		if len(eligible) == 0 {
+			continue
+		}
+
+		var amount int64
+		switch promo.Type {
+		case "percentage":
+			amount = applyPercentage(b.LineNet, eligible, promo.Value)
+		case "fixed":
+			amount = applyFixed(b.LineNet, eligible, ToCents(promo.Value))
+		case "bogo":
+			amount = applyBOGO(b.LineNet, lines, eligible)
+		}
+		if amount == 0 {
+			continue
+		}
+
+		b.Discount += amount
+		b.Discounts = append(b.Discounts, models.OrderDiscount{
+			PromotionID: promo.ID,
+			Description: promo.Name,
+			Amount:      FromCents(amount),
+		})
+	}
+
+	b.updateTotal()
+	return b
+}
+
+// AddTaxes начисляет налоги на суммы позиций после скидок.
+// Позиция облагается ставками своих категорий, а если таких нет — ставками без категории.
+// Налог округляется один раз на ставку (а не на позицию), половина от нуля.
+// Налог, входящий в цену, выделяется из неё: net * r / (100 + сумма входящих ставок позиции).
+func (b *PriceBreakdown) AddTaxes(lines []models.PricingLine, rates []models.TaxRate) {
+	taxable := make([]int64, len(rates))
+	exact := make([]float64, len(rates))
+
+	for i, line := range lines {
+		applicable := make([]int, 0, 2)
+		for r, rate := range rates {
+			if rate.Active && rate.Category != "" && hasCategory(line.Categories, rate.Category) {
+				applicable = append(applicable, r)
+			}
+		}
+		if len(applicable) == 0 {
+			for r, rate := range rates {
+				if rate.Active && rate.Category == "" {
+					applicable = append(applicable, r)
+				}
+			}
+		}
+
+		var inclusiveSum float64
+		for _, r := range applicable {
+			if rates[r].Inclusive {
+				inclusiveSum += rates[r].Rate
+			}
+		}
+
+		for _, r := range applicable {
+			taxable[r] += b.LineNet[i]
+			if rates[r].Inclusive {
+				exact[r] += float64(b.LineNet[i]) * rates[r].Rate / (100 + inclusiveSum)
+			} else {
+				exact[r] += float64(b.LineNet[i]) * rates[r].Rate / 100
+			}
+		}
+	}
+
+	for r, rate := range rates {
+		if taxable[r] == 0 {
+			continue
+		}
+		amount := int64(math.Round(exact[r]))
+		b.Tax += amount
+		if !rate.Inclusive {
+			b.TaxExclusive += amount
+		}
+		b.TaxLines = append(b.TaxLines, models.OrderTaxLine{
+			TaxRateID:     rate.ID,
+			Name:          rate.Name,
+			Rate:          rate.Rate,
+			Inclusive:     rate.Inclusive,
+			TaxableAmount: FromCents(taxable[r]),
+			Amount:        FromCents(amount),
+		})
+	}
+
+	b.updateTotal()
+}
+
+// AddTip добавляет чаевые: процент от суммы после скидок (до налогов) или фиксированную сумму.
+func (b *PriceBreakdown) AddTip(amount float64, percent *float64) {
+	if percent != nil {
+		b.Tip = int64(math.Round(float64(b.Subtotal-b.Discount) * *percent / 100))
+	} else {
+		b.Tip = ToCents(amount)
+	}
+	b.updateTotal()
+}
+
+func (b *PriceBreakdown) updateTotal() {
+	b.Total = b.Subtotal - b.Discount + b.TaxExclusive + b.Tip
+}
+
+// PromotionApplies проверяет активность, период действия и окно happy hour.
+// Лимиты использования проверяются отдельно, по данным БД.
+func PromotionApplies(promo models.Promotion, at time.Time) bool {
+	if !promo.Active {
+		return false
+	}
+	if promo.StartsAt != nil && at.Before(*promo.StartsAt) {
+		return false
+	}
+	if promo.EndsAt != nil && !at.Before(*promo.EndsAt) {
+		return false
+	}
+	if promo.HappyHourStart != "" && promo.HappyHourEnd != "" {
+		start, err1 := parseClock(promo.HappyHourStart)
+		end, err2 := parseClock(promo.HappyHourEnd)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		local := at.Local()
+		now := local.Hour()*60 + local.Minute()
+		if start <= end {
+			return now >= start && now < end
+		}
+		// окно через полночь, например 22:00–02:00
+		return now >= start || now < end
+	}
+	return true
+}
+
+// ValidateClock проверяет время в формате "15:04".
+func ValidateClock(s string) error {
+	_, err := parseClock(s)
+	return err
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("время должно быть в формате ЧЧ:ММ: %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func hasCategory(categories []string, category string) bool {
+	for _, c := range categories {
+		if strings.EqualFold(c, category) {
+			return true
+		}
+	}
+	return false
+}
+
+func applyPercentage(net []int64, eligible []int, percent float64) int64 {
+	if percent > 100 {
+		percent = 100
+	}
+	var total int64
+	for _, i := range eligible {
+		d := int64(math.Round(float64(net[i]) * percent / 100))
+		net[i] -= d
+		total += d
+	}
+	return total
+}
+
+// applyFixed списывает сумму с позиций по порядку, пока она не кончится.
+func applyFixed(net []int64, eligible []int, amount int64) int64 {
+	var total int64
+	for _, i := range eligible {
+		if amount == 0 {
+			break
+		}
+		d := net[i]
+		if d > amount {
+			d = amount
+		}
+		net[i] -= d
+		amount -= d
+		total += d
+	}
+	return total
+}
+
+// applyBOGO раскладывает все подходящие единицы по убыванию цены
+// и делает бесплатной каждую вторую.
+func applyBOGO(net []int64, lines []models.PricingLine, eligible []int) int64 {
+	type unit struct {
+		line  int
+		price int64
+	}
+	var units []unit
+	for _, i := range eligible {
+		if lines[i].Quantity <= 0 {
+			continue
+		}
+		// цена единицы с учётом предыдущих скидок
+		per := net[i] / int64(lines[i].Quantity)
+		for q := 0; q < lines[i].Quantity; q++ {
+			units = append(units, unit{line: i, price: per})
+		}
+	}
+	sort.SliceStable(units, func(a, b int) bool { return units[a].price > units[b].price })
+
+	var total int64
+	for k := 1; k < len(units); k += 2 {
+		u := units[k]
+		d := u.price
+		if d > net[u.line] {
+			d = net[u.line]
+		}
+		net[u.line] -= d
+		total += d
+	}
+	return total
+}
@@ -0,0 +1,299 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"frappuccino/models"
+	"html/template"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Ширина чека в символах: 42 — шрифт A на ленте 80 мм, 32 — лента 58 мм.
+const (
+	DefaultReceiptWidth = 42
+	MinReceiptWidth     = 24
+	MaxReceiptWidth     = 64
+)
+
+// Заголовок чека задаётся переменной окружения RECEIPT_HEADER.
+func receiptHeader() string {
+	if h := os.Getenv("RECEIPT_HEADER"); h != "" {
+		return h
+	}
+	return "Frappuccino"
+}
+
+type receiptLine struct {
+	text   string
+	center bool
+	bold   bool
+	large  bool
+}
+
+// receiptLines раскладывает чек по строкам фиксированной ширины.
+// Одна раскладка используется и для текста, и для ESC/POS.
+func receiptLines(r models.Receipt, width int) []receiptLine {
+	sep := strings.Repeat("-", width)
+	lines := []receiptLine{
+		{text: receiptHeader(), center: true, bold: true, large: true},
+		{text: fmt.Sprintf("Order #%d", r.Order.ID), center: true},
+		{text: r.Order.OrderDate.Local().Format("2006-01-02 15:04"), center: true},
+		{text: sep},
+	}
+
+	for _, item := range r.Items {
+		name := item.Name
+		if item.Size != "" {
+			name += " (" + item.Size + ")"
+		}
+		lines = append(lines, receiptLine{text: twoColumns(fmt.Sprintf("%d x %s", item.Quantity, name), money(item.LineTotal), width)})
+		if item.Quantity > 1 {
+			lines = append(lines, receiptLine{text: "    @ " + money(item.UnitPrice)})
+		}
+		for _, m := range item.Modifiers {
+			lines = append(lines, receiptLine{text: "  + " + m})
+		}
+		for _, c := range customizationLines(item.Customization) {
+			lines = append(lines, receiptLine{text: "  * " + c})
+		}
+	}
+
+	lines = append(lines, receiptLine{text: sep})
+	lines = append(lines, receiptLine{text: twoColumns("Subtotal", money(r.Order.Subtotal), width)})
+	for _, d := range r.Order.Discounts {
+		lines = append(lines, receiptLine{text: twoColumns(discountLabel(d), "-"+money(d.Amount), width)})
+	}
+	for _, t := range r.Order.TaxLines {
+		label := fmt.Sprintf("%s %s%%", t.Name, trimRate(t.Rate))
+		if t.Inclusive {
+			label += " (incl.)"
+		}
+		lines = append(lines, receiptLine{text: twoColumns(label, money(t.Amount), width)})
+	}
+	if r.Order.TipAmount > 0 {
+		lines = append(lines, receiptLine{text: twoColumns("Tip", money(r.Order.TipAmount), width)})
+	}
+	lines = append(lines, receiptLine{text: twoColumns("TOTAL", money(r.Order.TotalAmount), width), bold: true})
+
+	if len(r.Payments) > 0 {
+		lines = append(lines, receiptLine{text: sep})
+		for _, p := range r.Payments {
+			lines = append(lines, receiptLine{text: twoColumns("Paid ("+p.Method+")", money(p.Amount), width)})
+		}
+		if change := r.PaidTotal - r.Order.TotalAmount; change > 0.004 {
+			lines = append(lines, receiptLine{text: twoColumns("Change", money(change), width)})
+		} else if change < -0.004 {
+			lines = append(lines, receiptLine{text: twoColumns("Due", money(-change), width), bold: true})
+		}
+	}
+
+	lines = append(lines, receiptLine{text: sep})
+	lines = append(lines, receiptLine{text: "Thank you!", center: true})
+	return lines
+}
+
+// RenderReceiptText — чек обычным текстом.
+func RenderReceiptText(r models.Receipt, width int) string {
+	var sb strings.Builder
+	for _, l := range receiptLines(r, width) {
+		text := fit(l.text, width)
+		if l.center {
+			text = strings.Repeat(" ", (width-utf8.RuneCountInString(text))/2) + text
+		}
+		sb.WriteString(text)
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// Команды ESC/POS
+var (
+	escInit        = []byte{0x1B, 0x40}
+	escAlignLeft   = []byte{0x1B, 0x61, 0x00}
+	escAlignCenter = []byte{0x1B, 0x61, 0x01}
+	escBoldOn      = []byte{0x1B, 0x45, 0x01}
+	escBoldOff     = []byte{0x1B, 0x45, 0x00}
+	escSizeDouble  = []byte{0x1D, 0x21, 0x11}
+	escSizeNormal  = []byte{0x1D, 0x21, 0x00}
+	escFeedAndCut  = []byte{0x1D, 0x56, 0x42, 0x03} // подача 3 строк и частичный отрез
+)
+
+// RenderReceiptESCPOS — сырые байты для термопринтера. Символы вне ASCII
+// заменяются на '?', так как кодовая страница принтера не задаётся.
+func RenderReceiptESCPOS(r models.Receipt, width int) []byte {
+	var buf bytes.Buffer
+	buf.Write(escInit)
+
+	for _, l := range receiptLines(r, width) {
+		lineWidth := width
+		if l.large {
+			lineWidth = width / 2
+		}
+		if l.center {
+			buf.Write(escAlignCenter)
+		}
+		if l.bold {
+			buf.Write(escBoldOn)
+		}
+		if l.large {
+			buf.Write(escSizeDouble)
+		}
+
+		buf.WriteString(toASCII(fit(l.text, lineWidth)))
+		buf.WriteByte('\n')
+
+		if l.large {
+			buf.Write(escSizeNormal)
+		}
+		if l.bold {
+			buf.Write(escBoldOff)
+		}
+		if l.center {
+			buf.Write(escAlignLeft)
+		}
+	}
+
+	buf.Write(escFeedAndCut)
+	return buf.Bytes()
+}
+
+// discountLabel — подпись скидки в чеке. Оплата баллами подписывается по числу
+// баллов, а не по сохранённому описанию.
+func discountLabel(d models.OrderDiscount) string {
+	if d.Points > 0 {
+		return pointsDiscountLabel(d.Points)
+	}
+	return d.Description
+}
+
+var receiptHTML = template.Must(template.New("receipt").Funcs(template.FuncMap{
+	"money":          money,
+	"rate":           trimRate,
+	"customizations": customizationLines,
+	"neg":            func(v float64) float64 { return -v },
+	"discount":       discountLabel,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Order #{{.Order.ID}}</title>
+<style>
+body { font-family: monospace; width: 80mm; margin: 0 auto; }
+h1 { text-align: center; font-size: 1.4em; margin-bottom: 0; }
+.center { text-align: center; }
+table { width: 100%; border-collapse: collapse; }
+td.amount { text-align: right; white-space: nowrap; }
+.note { padding-left: 1.5em; color: #555; }
+.total td { font-weight: bold; border-top: 1px dashed #000; }
+hr { border: 0; border-top: 1px dashed #000; }
+@media print { body { width: auto; } }
+</style>
+</head>
+<body>
+<h1>{{.Header}}</h1>
+<p class="center">Order #{{.Order.ID}}<br>{{.Order.OrderDate.Local.Format "2006-01-02 15:04"}}</p>
+<hr>
+<table>
+{{range .Items}}<tr><td>{{.Quantity}} x {{.Name}}{{if .Size}} ({{.Size}}){{end}}</td><td class="amount">{{money .LineTotal}}</td></tr>
+{{range .Modifiers}}<tr><td class="note" colspan="2">+ {{.}}</td></tr>
+{{end}}{{range customizations .Customization}}<tr><td class="note" colspan="2">* {{.}}</td></tr>
+{{end}}{{end}}</table>
+<hr>
+<table>
+<tr><td>Subtotal</td><td class="amount">{{money .Order.Subtotal}}</td></tr>
+{{range .Order.Discounts}}<tr><td>{{discount .}}</td><td class="amount">-{{money .Amount}}</td></tr>
+{{end}}{{range .Order.TaxLines}}<tr><td>{{.Name}} {{rate .Rate}}%{{if .Inclusive}} (incl.){{end}}</td><td class="amount">{{money .Amount}}</td></tr>
+{{end}}{{if gt .Order.TipAmount 0.0}}<tr><td>Tip</td><td class="amount">{{money .Order.TipAmount}}</td></tr>
+{{end}}<tr class="total"><td>TOTAL</td><td class="amount">{{money .Order.TotalAmount}}</td></tr>
+</table>
+{{if .Payments}}<hr>
+<table>
+{{range .Payments}}<tr><td>Paid ({{.Method}})</td><td class="amount">{{money .Amount}}</td></tr>
+{{end}}{{if gt .Change 0.004}}<tr><td>Change</td><td class="amount">{{money .Change}}</td></tr>
+{{else if lt .Change -0.004}}<tr><td><b>Due</b></td><td class="amount"><b>{{money (neg .Change)}}</b></td></tr>
+{{end}}</table>
+{{end}}<hr>
+<p class="center">Thank you!</p>
+</body>
+</html>
+`))
+
+// RenderReceiptHTML — чек для печати из браузера.
+func RenderReceiptHTML(r models.Receipt) ([]byte, error) {
+	var buf bytes.Buffer
+	data := struct {
+		models.Receipt
+		Header string
+		Change float64
+	}{r, receiptHeader(), r.PaidTotal - r.Order.TotalAmount}
+
+	if err := receiptHTML.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("не удалось сформировать HTML чека: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func money(v float64) string {
+	return fmt.Sprintf("%.2f", v)
+}
+
+func trimRate(rate float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.3f", rate), "0"), ".")
+}
+
+// customizationLines превращает произвольный JSON пожеланий в строки "ключ: значение"
+// в алфавитном порядке ключей.
+func customizationLines(c map[string]interface{}) []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, 0, len(keys))
+	for _, k := range keys {
+		switch v := c[k].(type) {
+		case bool:
+			if v {
+				lines = append(lines, k)
+			} else {
+				lines = append(lines, "no "+k)
+			}
+		default:
+			lines = append(lines, fmt.Sprintf("%s: %v", k, v))
+		}
+	}
+	return lines
+}
+
+// twoColumns прижимает left влево, right вправо; слишком длинный left обрезается.
+func twoColumns(left, right string, width int) string {
+	space := width - utf8.RuneCountInString(right) - 1
+	left = fit(left, space)
+	return left + strings.Repeat(" ", width-utf8.RuneCountInString(left)-utf8.RuneCountInString(right)) + right
+}
+
+func fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	return string([]rune(s)[:width])
+}
+
+func toASCII(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if r < 0x20 || r > 0x7E {
+			sb.WriteByte('?')
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,47 @@
+package utils
+
+import "frappuccino/models"
+
+// ApplyReorderRule дополняет строку расчётом: средний расход, прогноз на момент
+// поставки и количество к заказу. used — расход за days дней, unitPrice — текущая
+// цена за единицу (для оценки, если у ингредиента нет поставщика).
+// Возвращает false, если дозаказ не нужен.
+func ApplyReorderRule(s *models.ReorderSuggestion, used models.Quantity, days int, unitPrice float64) (bool, error) {
+	s.AvgDailyUsage = used.Div(days)
+	leadUsage, err := s.AvgDailyUsage.Mul(s.LeadTimeDays)
+	if err != nil {
+		return false, err
+	}
+	s.ProjectedAtArrival = s.Quantity + s.OnOrder - leadUsage
+
+	if s.ProjectedAtArrival >= s.ParLevel {
+		return false, nil
+	}
+
+	s.SuggestedQuantity = s.ParLevel + leadUsage - s.Quantity - s.OnOrder
+	if s.SuggestedQuantity <= 0 {
+		return false, nil
+	}
+
+	// Заказываем целыми упаковками, округляя вверх
+	if s.PackSize > 0 {
+		if err := SetReorderPacks(s, int((s.SuggestedQuantity+s.PackSize-1)/s.PackSize)); err != nil {
+			return false, err
+		}
+	} else {
+		s.EstimatedCost = FromCents(ToCents(s.SuggestedQuantity.Float64() * unitPrice))
+	}
+	return true, nil
+}
+
+// SetReorderPacks фиксирует число упаковок и пересчитывает количество и стоимость.
+func SetReorderPacks(s *models.ReorderSuggestion, packs int) error {
+	quantity, err := s.PackSize.Mul(packs)
+	if err != nil {
+		return err
+	}
+	s.Packs = packs
+	s.SuggestedQuantity = quantity
+	s.EstimatedCost = FromCents(int64(packs) * ToCents(s.PackPrice))
+	return nil
+}
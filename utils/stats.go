@@ -0,0 +1,19 @@
+package utils
+
+import "sort"
+
+// Percentile считает перцентиль p (0..1) с линейной интерполяцией между соседними
+// значениями — так же, как percentile_cont в PostgreSQL. values сортируется на месте.
+func Percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+
+	pos := p * float64(len(values)-1)
+	lower := int(pos)
+	if lower >= len(values)-1 {
+		return values[len(values)-1]
+	}
+	return values[lower] + (pos-float64(lower))*(values[lower+1]-values[lower])
+}
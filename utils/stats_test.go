@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		p      float64
+		want   float64
+	}{
+		{name: "пусто", values: nil, p: 0.5, want: 0},
+		{name: "одно значение", values: []float64{42}, p: 0.9, want: 42},
+		{name: "медиана нечётного числа", values: []float64{30, 10, 20}, p: 0.5, want: 20},
+		{name: "медиана чётного числа", values: []float64{40, 10, 30, 20}, p: 0.5, want: 25},
+		{name: "90-й перцентиль с интерполяцией", values: []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, p: 0.9, want: 9.1},
+		{name: "минимум", values: []float64{5, 3, 9}, p: 0, want: 3},
+		{name: "максимум", values: []float64{5, 3, 9}, p: 1, want: 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Percentile(tt.values, tt.p); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Percentile(%v, %.2f) = %v, ожидалось %v", tt.values, tt.p, got, tt.want)
+			}
+		})
+	}
+}
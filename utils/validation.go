@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"database/sql"
 	"fmt"
 	"frappuccino/db"
 	"frappuccino/models"
@@ -17,7 +18,23 @@ func IsValidSize(validSizes []string, size string) bool {
 	return false
 }
 
+// Проверяет рецепт: количество каждого ингредиента больше нуля, сам ингредиент
+// есть на складе и не в архиве.
 func ValidateIngredients(ingredients []models.IngredientInfo) error {
+	ids := make([]int, 0, len(ingredients))
+	for _, ingredient := range ingredients {
+		// Нулевое количество сделало бы блюдо неограниченно доступным,
+		// отрицательное — увеличивало бы остаток при продаже
+		if ingredient.QuantityRequired <= 0 {
+			return fmt.Errorf("количество ингредиента с ID %d должно быть больше 0", ingredient.IngredientID)
+		}
+		ids = append(ids, ingredient.IngredientID)
+	}
+	return ValidateIngredientIDs(ids)
+}
+
+// Проверяет, что ингредиенты есть на складе и не в архиве.
+func ValidateIngredientIDs(ids []int) error {
 	// Подключаемся к базе данных
 	dbConn, err := db.InitDB()
 	if err != nil {
@@ -26,15 +43,71 @@ func ValidateIngredients(ingredients []models.IngredientInfo) error {
 	defer dbConn.Close()
 
 	// Проверяем каждый ингредиент
-	for _, ingredient := range ingredients {
+	for _, id := range ids {
+		var archived bool
+		query := `SELECT archived_at IS NOT NULL FROM inventory WHERE id = $1`
+		err := dbConn.QueryRow(query, id).Scan(&archived)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("ингредиент с ID %d не найден в инвентаре", id)
+		} else if err != nil {
+			return fmt.Errorf("ошибка при проверке ингредиента с ID %d: %v", id, err)
+		}
+		if archived {
+			return fmt.Errorf("ингредиент с ID %d в архиве", id)
+		}
+	}
+
+	return nil
+}
+
+// Проверяет размеры блюда: допустимый размер без повторов, положительная цена,
+// положительный коэффициент рецепта и существующие ингредиенты.
+func ValidateVariants(validSizes []string, variants []models.MenuItemVariant) error {
+	seen := make(map[string]bool)
+	for _, v := range variants {
+		if !IsValidSize(validSizes, v.Size) {
+			return fmt.Errorf("недопустимый размер варианта: %q", v.Size)
+		}
+		size := strings.ToLower(v.Size)
+		if seen[size] {
+			return fmt.Errorf("размер %s указан несколько раз", size)
+		}
+		seen[size] = true
+
+		if v.Price <= 0 {
+			return fmt.Errorf("цена размера %s должна быть больше 0", size)
+		}
+		if v.RecipeMultiplier != nil && *v.RecipeMultiplier <= 0 {
+			return fmt.Errorf("коэффициент рецепта размера %s должен быть больше 0", size)
+		}
+		if len(v.Ingredients) > 0 {
+			if err := ValidateIngredients(v.Ingredients); err != nil {
+				return fmt.Errorf("размер %s: %v", size, err)
+			}
+		}
+	}
+	return nil
+}
+
+func ValidateModifierIDs(modifierIDs []int) error {
+	if len(modifierIDs) == 0 {
+		return nil
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		return fmt.Errorf("не удалось подключиться к БД: %v", err)
+	}
+	defer dbConn.Close()
+
+	for _, id := range modifierIDs {
 		var exists bool
-		query := `SELECT EXISTS(SELECT 1 FROM inventory WHERE id = $1)`
-		err := dbConn.QueryRow(query, ingredient.IngredientID).Scan(&exists)
+		err := dbConn.QueryRow(`SELECT EXISTS(SELECT 1 FROM modifiers WHERE id = $1)`, id).Scan(&exists)
 		if err != nil {
-			return fmt.Errorf("ошибка при проверке ингредиента с ID %d: %v", ingredient.IngredientID, err)
+			return fmt.Errorf("ошибка при проверке модификатора с ID %d: %v", id, err)
 		}
 		if !exists {
-			return fmt.Errorf("ингредиент с ID %d не найден в инвентаре", ingredient.IngredientID)
+			return fmt.Errorf("модификатор с ID %d не найден", id)
 		}
 	}
 
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"frappuccino/models"
+	"math"
+)
+
+// StockMovement — движение из журнала для пересчёта стоимости.
+type StockMovement struct {
+	Change   models.Quantity
+	UnitCost *float64
+}
+
+type costLayer struct {
+	quantity float64
+	cost     float64
+}
+
+// ReplayValuation проводит движения по порядку начиная с остатка opening по цене
+// openingCost и возвращает остаток и его стоимость.
+//
+// average: приход пересчитывает средневзвешенную цену, расход её не меняет.
+// fifo: каждый приход — отдельный слой, расход списывает слои от самого старого.
+// Приход без цены оценивается по последней известной цене.
+func ReplayValuation(method string, opening models.Quantity, openingCost float64, moves []StockMovement) (models.Quantity, float64) {
+	qty := opening
+	lastCost := openingCost
+	avg := openingCost
+	var layers []costLayer
+	if opening > 0 {
+		layers = append(layers, costLayer{opening.Float64(), openingCost})
+	}
+
+	for _, m := range moves {
+		cost := lastCost
+		if m.UnitCost != nil {
+			cost = *m.UnitCost
+		}
+
+		if m.Change > 0 {
+			if qty > 0 {
+				avg = (qty.Float64()*avg + m.Change.Float64()*cost) / (qty + m.Change).Float64()
+			} else {
+				avg = cost
+			}
+			layers = append(layers, costLayer{m.Change.Float64(), cost})
+			if m.UnitCost != nil {
+				lastCost = cost
+			}
+		} else {
+			need := -m.Change.Float64()
+			for need > 0 && len(layers) > 0 {
+				take := math.Min(need, layers[0].quantity)
+				layers[0].quantity -= take
+				need -= take
+				if layers[0].quantity <= 0 {
+					layers = layers[1:]
+				}
+			}
+		}
+		qty += m.Change
+	}
+
+	if qty <= 0 {
+		return qty, 0
+	}
+	if method == models.ValuationFIFO {
+		var value float64
+		for _, l := range layers {
+			value += l.quantity * l.cost
+		}
+		return qty, FromCents(ToCents(value))
+	}
+	return qty, FromCents(ToCents(qty.Float64() * avg))
+}
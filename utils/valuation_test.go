@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"frappuccino/models"
+	"testing"
+)
+
+func cost(v float64) *float64 { return &v }
+
+func TestReplayValuation(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		opening     models.Quantity
+		openingCost float64
+		moves       []StockMovement
+		wantQty     models.Quantity
+		wantValue   float64
+	}{
+		{
+			name:   "fifo: расход списывает старый слой целиком и часть нового",
+			method: models.ValuationFIFO, opening: models.NewQuantity(10), openingCost: 1,
+			moves: []StockMovement{
+				{Change: models.NewQuantity(10), UnitCost: cost(2)},
+				{Change: models.NewQuantity(-15)},
+			},
+			wantQty: models.NewQuantity(5), wantValue: 10,
+		},
+		{
+			name:   "fifo: остаток из двух слоёв по их ценам",
+			method: models.ValuationFIFO,
+			moves: []StockMovement{
+				{Change: models.NewQuantity(4), UnitCost: cost(3)},
+				{Change: models.NewQuantity(6), UnitCost: cost(5)},
+				{Change: models.NewQuantity(-2)},
+			},
+			wantQty: models.NewQuantity(8), wantValue: 2*3 + 6*5,
+		},
+		{
+			name:   "fifo: приход без цены — по последней известной",
+			method: models.ValuationFIFO, opening: models.NewQuantity(2), openingCost: 4,
+			moves: []StockMovement{
+				{Change: models.NewQuantity(3)},
+			},
+			wantQty: models.NewQuantity(5), wantValue: 20,
+		},
+		{
+			name:   "average: приход пересчитывает среднюю, расход её не меняет",
+			method: models.ValuationAverage, opening: models.NewQuantity(10), openingCost: 1,
+			moves: []StockMovement{
+				{Change: models.NewQuantity(10), UnitCost: cost(2)},
+				{Change: models.NewQuantity(-15)},
+			},
+			wantQty: models.NewQuantity(5), wantValue: 7.5,
+		},
+		{
+			name:   "average: после обнуления остатка средняя берётся из прихода",
+			method: models.ValuationAverage, opening: models.NewQuantity(5), openingCost: 1,
+			moves: []StockMovement{
+				{Change: models.NewQuantity(-5)},
+				{Change: models.NewQuantity(10), UnitCost: cost(3)},
+			},
+			wantQty: models.NewQuantity(10), wantValue: 30,
+		},
+		{
+			name:   "average: дробная средняя округляется до центов",
+			method: models.ValuationAverage, opening: models.NewQuantity(3), openingCost: 1,
+			moves: []StockMovement{
+				{Change: models.NewQuantity(3), UnitCost: cost(1.01)},
+				{Change: models.NewQuantity(-5)},
+			},
+			wantQty: models.NewQuantity(1), wantValue: 1.01, // средняя 1.005
+		},
+		{
+			name:   "отрицательный остаток не имеет стоимости",
+			method: models.ValuationFIFO, opening: models.NewQuantity(1), openingCost: 2,
+			moves: []StockMovement{
+				{Change: models.NewQuantity(-3)},
+			},
+			wantQty: models.NewQuantity(-2), wantValue: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qty, value := ReplayValuation(tt.method, tt.opening, tt.openingCost, tt.moves)
+			if qty != tt.wantQty || value != tt.wantValue {
+				t.Errorf("ReplayValuation() = %s, %.2f; ожидалось %s, %.2f", qty, value, tt.wantQty, tt.wantValue)
+			}
+		})
+	}
+}